@@ -4,8 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"effective-mobile-song-library/config"
+	"effective-mobile-song-library/internal/repository/sqlite"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	pgMigrate "github.com/golang-migrate/migrate/v4/database/postgres"
+	sqliteMigrate "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 func openDB(c config.Config) (*sql.DB, error) {
@@ -27,3 +35,50 @@ func openDB(c config.Config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// openSQLite opens the SQLite database file, creating it if it does not exist.
+func openSQLite(c config.Config) (*sql.DB, error) {
+	db, err := sql.Open(sqlite.DriverName, sqlite.DSN(c.SQLitePath))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open the database: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open the database file %s: %s", c.SQLitePath, err)
+	}
+
+	return db, nil
+}
+
+// migrateDB applies the pending migrations of the storage, each database
+// having its own migration set.
+func migrateDB(db *sql.DB, storage string) error {
+	var migrationDriver database.Driver
+	var err error
+	source := "file://migrations"
+
+	switch storage {
+	case "sqlite":
+		migrationDriver, err = sqliteMigrate.WithInstance(db, &sqliteMigrate.Config{})
+		source = "file://migrations/sqlite"
+	default:
+		migrationDriver, err = pgMigrate.WithInstance(db, &pgMigrate.Config{})
+	}
+	if err != nil {
+		return err
+	}
+
+	migrator, err := migrate.NewWithDatabaseInstance(source, storage, migrationDriver)
+	if err != nil {
+		return err
+	}
+	err = migrator.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
@@ -1,15 +1,17 @@
 package cmd
 
 import (
-	"github.com/golang-migrate/migrate/v4"
-	pgMigrate "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"context"
+	"fmt"
 
 	"effective-mobile-song-library/config"
 	_ "effective-mobile-song-library/docs"
 	"effective-mobile-song-library/internal/delivery/http"
+	"effective-mobile-song-library/internal/repository/cache"
 	pgDB "effective-mobile-song-library/internal/repository/db"
 	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/internal/repository/memory"
+	"effective-mobile-song-library/internal/repository/sqlite"
 	"effective-mobile-song-library/internal/service"
 	"effective-mobile-song-library/pkg/logger"
 )
@@ -24,39 +26,90 @@ func Start() {
 		logger.PrintError(err, nil)
 	}
 
-	// Connect to DB
-	db, err := openDB(*cfg)
-	if err != nil {
-		logger.PrintFatal(err, nil)
+	// prepare repo
+	var songsRepo service.SongStorage
+	var cacheStore cache.Store
+	switch cfg.Storage {
+	case "postgres":
+		// Connect to DB
+		db, err := openDB(*cfg)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		defer db.Close()
+
+		// Database migrations
+		err = migrateDB(db, "postgres")
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+
+		songsRepo = pgDB.NewSongsRepository(db, cfg.DBTimeout)
+		if cfg.CacheStore == "postgres" {
+			cacheStore = pgDB.NewSongInfoCache(db, cfg.DBTimeout)
+		}
+	case "sqlite":
+		db, err := openSQLite(*cfg)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		defer db.Close()
+
+		err = migrateDB(db, "sqlite")
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+
+		songsRepo = sqlite.NewSongsRepository(db, cfg.DBTimeout)
+	case "memory":
+		logger.PrintInfo("using in-memory storage, data will be lost on restart", nil)
+		songsRepo = memory.NewSongsRepository()
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown storage %q", cfg.Storage), nil)
 	}
-	defer db.Close()
 
-	// Database migrations
-	migrationDriver, err := pgMigrate.WithInstance(db, &pgMigrate.Config{})
-	if err != nil {
-		logger.PrintFatal(err, nil)
+	switch cfg.CacheStore {
+	case "memory":
+		cacheStore = cache.NewMemoryStore(cfg.CacheSize)
+	case "postgres":
+		if cacheStore == nil {
+			logger.PrintFatal(fmt.Errorf("cache store %q requires postgres storage", cfg.CacheStore), nil)
+		}
+	case "none":
+	default:
+		logger.PrintFatal(fmt.Errorf("unknown cache store %q", cfg.CacheStore), nil)
 	}
-	migrator, err := migrate.NewWithDatabaseInstance("file://migrations", "postgres", migrationDriver)
-	if err != nil {
-		logger.PrintFatal(err, nil)
+
+	providers := make([]service.Provider, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		var provider service.Provider = external.NewApiClient(p, external.NewHTTPClient(p))
+		if cacheStore != nil {
+			provider = cache.NewProvider(provider, cacheStore, cfg.CacheTTL, cfg.CacheNegativeTTL)
+		}
+		providers = append(providers, provider)
 	}
-	err = migrator.Up()
-	if err != nil && err != migrate.ErrNoChange {
+
+	// service layer
+	songLibraryService := service.NewSongLibraryService(songsRepo, providers, cfg)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	// background jobs, the ones pending from the last run are resumed
+	err = songLibraryService.StartJobs(ctx, cfg.JobWorkers)
+	if err != nil {
 		logger.PrintFatal(err, nil)
 	}
 
-	// prepare repo
-	songsRepo := pgDB.NewSongsRepository(db)
-	apiClient := external.NewApiClient(cfg)
-
-	// service layer
-	songLibraryService := service.NewSongLibraryService(songsRepo, apiClient)
+	// stale songs are looked up again in the background
+	songLibraryService.StartReenrichment(ctx)
 
 	// handler
 	handler := http.NewHandler(songLibraryService)
 
 	srv := NewServer(
 		handler,
+		songLibraryService,
 		cfg,
 	)
 
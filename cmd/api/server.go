@@ -16,14 +16,21 @@ import (
 	"effective-mobile-song-library/pkg/logger"
 )
 
+// jobDrainer runs the background jobs left when the server shuts down.
+type jobDrainer interface {
+	DrainJobs(ctx context.Context) error
+}
+
 type httpserver struct {
 	handler *httphandl.Handler
+	jobs    jobDrainer
 	config  *config.Config
 }
 
-func NewServer(handler *httphandl.Handler, cfg *config.Config) httpserver {
+func NewServer(handler *httphandl.Handler, jobs jobDrainer, cfg *config.Config) httpserver {
 	return httpserver{
 		handler: handler,
+		jobs:    jobs,
 		config:  cfg,
 	}
 }
@@ -56,7 +63,15 @@ func (s httpserver) Start() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
-		shutdownError <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			shutdownError <- err
+			return
+		}
+
+		// no new jobs come in anymore, finish the pending ones
+		logger.PrintInfo("draining background jobs", nil)
+		shutdownError <- s.jobs.DrainJobs(ctx)
 	}()
 
 	logger.PrintInfo("starting server", map[string]any{
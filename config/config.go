@@ -1,26 +1,120 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Port           int    `mapstructure:"PORT"`
-	DBHost         string `mapstructure:"DB_HOST"`
-	DBPort         int    `mapstructure:"DB_PORT"`
-	DBUser         string `mapstructure:"DB_USER"`
-	DBPassword     string `mapstructure:"DB_PASSWORD"`
-	DBName         string `mapstructure:"DB_NAME"`
-	ExternalAPIURL string `mapstructure:"EXTERNAL_API_URL"`
+	Port               int           `mapstructure:"PORT"`
+	DBHost             string        `mapstructure:"DB_HOST"`
+	DBPort             int           `mapstructure:"DB_PORT"`
+	DBUser             string        `mapstructure:"DB_USER"`
+	DBPassword         string        `mapstructure:"DB_PASSWORD"`
+	DBName             string        `mapstructure:"DB_NAME"`
+	ExternalAPIURL     string        `mapstructure:"EXTERNAL_API_URL"`
+	FuzzyThreshold     float64       `mapstructure:"FUZZY_THRESHOLD"`
+	TrashRetention     time.Duration `mapstructure:"TRASH_RETENTION"`
+	DBTimeout          time.Duration `mapstructure:"DB_TIMEOUT"`
+	ExternalAPITimeout time.Duration `mapstructure:"EXTERNAL_API_TIMEOUT"`
+	Storage            string        `mapstructure:"STORAGE"`
+	SQLitePath         string        `mapstructure:"SQLITE_PATH"`
+	BatchConcurrency   int           `mapstructure:"BATCH_CONCURRENCY"`
+	JobWorkers         int           `mapstructure:"JOB_WORKERS"`
+	// JobLease is how long a job may go without its worker renewing it
+	// before it is taken for abandoned, e.g. by a crashed instance, and run
+	// again. The worker renews it every third of the lease.
+	JobLease time.Duration `mapstructure:"JOB_LEASE"`
+
+	ExternalAPIAttemptTimeout   time.Duration `mapstructure:"EXTERNAL_API_ATTEMPT_TIMEOUT"`
+	ExternalAPIRetries          int           `mapstructure:"EXTERNAL_API_RETRIES"`
+	ExternalAPIRetryDelay       time.Duration `mapstructure:"EXTERNAL_API_RETRY_DELAY"`
+	ExternalAPIBreakerThreshold int           `mapstructure:"EXTERNAL_API_BREAKER_THRESHOLD"`
+	ExternalAPIBreakerCooldown  time.Duration `mapstructure:"EXTERNAL_API_BREAKER_COOLDOWN"`
+
+	// Providers are the sources of song details in the order they are asked,
+	// see loadProviders.
+	Providers        []Provider `mapstructure:"-"`
+	MergeReleaseDate string     `mapstructure:"MERGE_RELEASE_DATE"`
+	MergeLink        string     `mapstructure:"MERGE_LINK"`
+	MergeText        string     `mapstructure:"MERGE_TEXT"`
+
+	// CacheStore is where the answers of the providers are cached:
+	// memory, postgres or none.
+	CacheStore       string        `mapstructure:"CACHE_STORE"`
+	CacheSize        int           `mapstructure:"CACHE_SIZE"`
+	CacheTTL         time.Duration `mapstructure:"CACHE_TTL"`
+	CacheNegativeTTL time.Duration `mapstructure:"CACHE_NEGATIVE_TTL"`
+
+	// ReenrichInterval is how often the stale songs are looked up again,
+	// zero disables it.
+	ReenrichInterval   time.Duration `mapstructure:"REENRICH_INTERVAL"`
+	ReenrichMaxAge     time.Duration `mapstructure:"REENRICH_MAX_AGE"`
+	ReenrichMissingAge time.Duration `mapstructure:"REENRICH_MISSING_AGE"`
+	ReenrichRetryAge   time.Duration `mapstructure:"REENRICH_RETRY_AGE"`
+	ReenrichBatchSize  int           `mapstructure:"REENRICH_BATCH_SIZE"`
 }
 
+// Provider configures a source of song details, an API like the one
+// at EXTERNAL_API_URL.
+type Provider struct {
+	Name             string
+	URL              string
+	Timeout          time.Duration
+	AttemptTimeout   time.Duration
+	Retries          int
+	RetryDelay       time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// Merge rules deciding which provider supplies a field of the song details.
+// A rule can also list provider names, the first of them having the field wins.
+const (
+	// MergeFirst takes the field from the first provider of the chain having it.
+	MergeFirst = "first"
+	// MergeLongest takes the longest value, e.g. the most precise release
+	// date or the lyrics with the most verses.
+	MergeLongest = "longest"
+)
+
 func Load() (*Config, error) {
 	config := &Config{}
 
 	viper.SetConfigFile(".env")
 
+	viper.SetDefault("FUZZY_THRESHOLD", 0.3)
+	viper.SetDefault("TRASH_RETENTION", "720h")
+	viper.SetDefault("DB_TIMEOUT", "3s")
+	viper.SetDefault("EXTERNAL_API_TIMEOUT", "10s")
+	viper.SetDefault("STORAGE", "postgres")
+	viper.SetDefault("SQLITE_PATH", "songs.db")
+	viper.SetDefault("BATCH_CONCURRENCY", 4)
+	viper.SetDefault("JOB_WORKERS", 4)
+	viper.SetDefault("JOB_LEASE", "10m")
+	viper.SetDefault("EXTERNAL_API_ATTEMPT_TIMEOUT", "3s")
+	viper.SetDefault("EXTERNAL_API_RETRIES", 3)
+	viper.SetDefault("EXTERNAL_API_RETRY_DELAY", "200ms")
+	viper.SetDefault("EXTERNAL_API_BREAKER_THRESHOLD", 5)
+	viper.SetDefault("EXTERNAL_API_BREAKER_COOLDOWN", "30s")
+	viper.SetDefault("MERGE_RELEASE_DATE", MergeFirst)
+	viper.SetDefault("MERGE_LINK", MergeFirst)
+	viper.SetDefault("MERGE_TEXT", MergeFirst)
+	viper.SetDefault("CACHE_STORE", "memory")
+	viper.SetDefault("CACHE_SIZE", 1000)
+	viper.SetDefault("CACHE_TTL", "24h")
+	viper.SetDefault("CACHE_NEGATIVE_TTL", "10m")
+	viper.SetDefault("REENRICH_INTERVAL", "0s")
+	viper.SetDefault("REENRICH_MAX_AGE", "720h")
+	viper.SetDefault("REENRICH_MISSING_AGE", "24h")
+	viper.SetDefault("REENRICH_RETRY_AGE", "6h")
+	viper.SetDefault("REENRICH_BATCH_SIZE", 100)
+
 	err := viper.ReadInConfig()
 	if err != nil {
 		log.Fatal(".env file is not found: ", err)
@@ -31,5 +125,97 @@ func Load() (*Config, error) {
 		log.Fatal("An error occured during config unmarshalling: ", err)
 	}
 
+	config.Providers, err = loadProviders(config)
+	if err != nil {
+		log.Fatal("An error occured during providers configuration: ", err)
+	}
+
+	if config.JobLease <= 0 {
+		log.Fatal("An error occured during jobs configuration: JOB_LEASE must be positive")
+	}
+
+	for _, rule := range []string{config.MergeReleaseDate, config.MergeLink, config.MergeText} {
+		err = checkMergeRule(rule, config.Providers)
+		if err != nil {
+			log.Fatal("An error occured during merge rules configuration: ", err)
+		}
+	}
+
 	return config, nil
 }
+
+// loadProviders reads the comma-separated provider names of PROVIDERS, every
+// provider is configured with the PROVIDER_<NAME>_ settings, e.g.
+// PROVIDER_BACKUP_URL. Only the URL is required, the other settings default to
+// the EXTERNAL_API ones. Without PROVIDERS the only provider is "main" at
+// EXTERNAL_API_URL.
+func loadProviders(config *Config) ([]Provider, error) {
+	defaults := Provider{
+		Name:             "main",
+		URL:              config.ExternalAPIURL,
+		Timeout:          config.ExternalAPITimeout,
+		AttemptTimeout:   config.ExternalAPIAttemptTimeout,
+		Retries:          config.ExternalAPIRetries,
+		RetryDelay:       config.ExternalAPIRetryDelay,
+		BreakerThreshold: config.ExternalAPIBreakerThreshold,
+		BreakerCooldown:  config.ExternalAPIBreakerCooldown,
+	}
+
+	names := viper.GetString("PROVIDERS")
+	if strings.TrimSpace(names) == "" {
+		return []Provider{defaults}, nil
+	}
+
+	var providers []Provider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.ContainsFunc(providers, func(p Provider) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("invalid or repeated provider name %q in PROVIDERS", name)
+		}
+
+		prefix := "PROVIDER_" + strings.ToUpper(name) + "_"
+		p := defaults
+		p.Name = name
+		p.URL = viper.GetString(prefix + "URL")
+		if p.URL == "" {
+			return nil, fmt.Errorf("%sURL is not set", prefix)
+		}
+		if viper.IsSet(prefix + "TIMEOUT") {
+			p.Timeout = viper.GetDuration(prefix + "TIMEOUT")
+		}
+		if viper.IsSet(prefix + "ATTEMPT_TIMEOUT") {
+			p.AttemptTimeout = viper.GetDuration(prefix + "ATTEMPT_TIMEOUT")
+		}
+		if viper.IsSet(prefix + "RETRIES") {
+			p.Retries = viper.GetInt(prefix + "RETRIES")
+		}
+		if viper.IsSet(prefix + "RETRY_DELAY") {
+			p.RetryDelay = viper.GetDuration(prefix + "RETRY_DELAY")
+		}
+		if viper.IsSet(prefix + "BREAKER_THRESHOLD") {
+			p.BreakerThreshold = viper.GetInt(prefix + "BREAKER_THRESHOLD")
+		}
+		if viper.IsSet(prefix + "BREAKER_COOLDOWN") {
+			p.BreakerCooldown = viper.GetDuration(prefix + "BREAKER_COOLDOWN")
+		}
+
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// checkMergeRule checks that the rule is either a known one
+// or a list of configured provider names.
+func checkMergeRule(rule string, providers []Provider) error {
+	if rule == MergeFirst || rule == MergeLongest {
+		return nil
+	}
+
+	for _, name := range strings.Split(rule, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.ContainsFunc(providers, func(p Provider) bool { return p.Name == name }) {
+			return fmt.Errorf("unknown provider %q in merge rule %q", name, rule)
+		}
+	}
+	return nil
+}
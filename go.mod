@@ -4,6 +4,7 @@ go 1.23.3
 
 require (
 	github.com/golang-migrate/migrate/v4 v4.18.1
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/spf13/viper v1.19.0
 	github.com/swaggo/swag v1.16.4
 )
@@ -0,0 +1,374 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"effective-mobile-song-library/internal/delivery"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// @Summary list albums
+// @Tags albums
+// @Description listing albums
+// @Accept json
+// @Produce json
+// @Param  group   query string  false  "name search by group"
+// @Param  title   query string  false  "search by a part of album's title"
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Success 200 {array} model.AlbumOut
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums [get]
+func (h *Handler) listAlbumsHandler(w http.ResponseWriter, r *http.Request) {
+	var filters model.AlbumFilters
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters.Group = readString(qs, "group", "")
+	filters.Title = readString(qs, "title", "")
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	if delivery.ValidateAlbumFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	albums, err := h.service.GetAlbums(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, albums, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary get album
+// @Tags albums
+// @Description get album with its track list
+// @Accept json
+// @Produce json
+// @Param  id path uint true "album id"
+// @Success 200 {object} model.Album
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums/{id} [get]
+func (h *Handler) showAlbumHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	album, err := h.service.GetAlbum(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, album, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary add album
+// @Tags albums
+// @Description add album
+// @Accept json
+// @Produce json
+// @Param  input body   model.AlbumInput   true  "album info struct"
+// @Success 201 {object} model.Album
+// @Failure 400 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums [post]
+func (h *Handler) addAlbumHandler(w http.ResponseWriter, r *http.Request) {
+	var input model.AlbumInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"input":  input,
+	})
+
+	album := &model.Album{Tracks: []model.AlbumTrack{}}
+	if input.Group != nil {
+		album.Group = *input.Group
+	}
+	if input.Title != nil {
+		album.Title = *input.Title
+	}
+	if input.ReleaseDate != nil {
+		album.ReleaseDate = *input.ReleaseDate
+	}
+
+	// validate
+	v := validator.New()
+	if delivery.ValidateAlbum(v, album); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.service.InsertAlbum(r.Context(), album)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusCreated, album, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary update album
+// @Tags albums
+// @Description update album data by ID
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "album ID"
+// @Param  input body   model.AlbumInput   true  "album info struct"
+// @Success 200 {object} model.Album
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums/{id} [patch]
+func (h *Handler) updateAlbumHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	album, err := h.service.GetAlbum(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input model.AlbumInput
+
+	err = jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"input":  input,
+	})
+
+	if input.Group != nil {
+		album.Group = *input.Group
+	}
+	if input.Title != nil {
+		album.Title = *input.Title
+	}
+	if input.ReleaseDate != nil {
+		album.ReleaseDate = *input.ReleaseDate
+	}
+
+	// validate
+	if delivery.ValidateAlbum(v, album); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.service.UpdateAlbum(r.Context(), album)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, album, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary delete album
+// @Tags albums
+// @Description delete album, the songs on it are kept
+// @Accept json
+// @Produce json
+// @Param  id   path      uint  true  "album ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums/{id} [delete]
+func (h *Handler) deleteAlbumHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+	})
+
+	err := h.service.DeleteAlbum(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, map[string]string{"message": "album successfully deleted"}, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary put song on album
+// @Tags albums
+// @Description put a song on the album at the given track number (appended if omitted), the following tracks are shifted. An already present song is moved.
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "album ID"
+// @Param  input body   model.AlbumTrackInput   true  "track struct"
+// @Success 200 {object} model.Album
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums/{id}/tracks [put]
+func (h *Handler) setAlbumTrackHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	var input model.AlbumTrackInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"input":  input,
+	})
+
+	if delivery.ValidateAlbumTrack(v, input); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	album, err := h.service.SetAlbumTrack(r.Context(), id, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, album, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary remove song from album
+// @Tags albums
+// @Description remove a song from the album, the following tracks are shifted up
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "album ID"
+// @Param  songId   path    uint  true  "song ID"
+// @Success 200 {object} model.Album
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /albums/{id}/tracks/{songId} [delete]
+func (h *Handler) removeAlbumTrackHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	songID := readIDParamFromPath(r, "songId", v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"id":      id,
+		"song_id": songID,
+	})
+
+	album, err := h.service.RemoveAlbumTrack(r.Context(), id, songID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, album, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
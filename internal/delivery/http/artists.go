@@ -0,0 +1,177 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"effective-mobile-song-library/internal/delivery"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// @Summary list groups
+// @Tags groups
+// @Description listing groups (artists)
+// @Accept json
+// @Produce json
+// @Param  name   query string  false  "search by a part of group's name"
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Success 200 {array} model.Artist
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /groups [get]
+func (h *Handler) listArtistsHandler(w http.ResponseWriter, r *http.Request) {
+	var filters model.ArtistFilters
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters.Name = readString(qs, "name", "")
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	if delivery.ValidateArtistFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	artists, err := h.service.GetArtists(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, artists, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary get group
+// @Tags groups
+// @Description get group (artist) by ID
+// @Accept json
+// @Produce json
+// @Param  id path uint true "group id"
+// @Success 200 {object} model.Artist
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /groups/{id} [get]
+func (h *Handler) showArtistHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	artist, err := h.service.GetArtist(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, artist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary list group songs
+// @Tags groups
+// @Description listing songs of a group (artist)
+// @Accept json
+// @Produce json
+// @Param  id path uint true "group id"
+// @Param  song   query string  false  "name search by song"
+// @Param  releaseDate   query string  false  "search by release date (YYYY, MM.YYYY or DD.MM.YYYY)"
+// @Param  releasedFrom   query string  false  "released on or after the date (YYYY, MM.YYYY or DD.MM.YYYY)"
+// @Param  releasedTo   query string  false  "released on or before the date (YYYY, MM.YYYY or DD.MM.YYYY)"
+// @Param  text   query string  false  "search by a part of song's text"
+// @Param  link   query string  false  "match link"
+// @Param  album   query string  false  "search by album title"
+// @Param  match   query string  false  "group and song matching: exact (default) or fuzzy (typo-tolerant, returns similarity score)"
+// @Param  threshold   query number  false  "similarity threshold for fuzzy matching between 0 and 1, default from config"
+// @Param  sort   query string  false  "comma-separated sort keys: id, group, song, releaseDate; prefix with - for descending, e.g. -releaseDate,group,song; default id"
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Param  after   query string  false  "keyset pagination: cursor of the row the page starts after, empty for the first page; the response then holds the cursors of the neighbouring pages instead of the metadata"
+// @Param  before   query string  false  "keyset pagination: cursor of the row the page ends before"
+// @Param  envelope   query bool  false  "wrap the songs with the pagination metadata, default true; false returns the bare array"
+// @Success 200 {object} model.Songs
+// @Header 200 {string} Link "RFC 8288 links to the first, prev, next and last pages"
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /groups/{id}/songs [get]
+func (h *Handler) listArtistSongsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	_, err := h.service.GetArtist(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	filters := readSongFilters(r.URL.Query(), v)
+	envelope := readBool(r.URL.Query(), "envelope", true, v)
+	filters.ArtistID = id
+	filters.Group = ""
+
+	if delivery.ValidateSongFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	songs, err := h.service.GetAll(r.Context(), filters)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidCursor):
+			invalidCursorResponse(w, r, filters)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	if links := songListLinks(r, filters, songs); links != "" {
+		headers.Set("Link", links)
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, songList(songs, envelope), headers)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,24 @@
+package http
+
+import (
+	"net/http"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/pkg/jsonutil"
+)
+
+// @Summary list re-enrichment runs
+// @Tags enrichment
+// @Description list the reports of the latest runs re-fetching the details of the stale songs from the providers, the latest first. Every song looked up is reported as updated (with the fields changed), unchanged, skipped (all its details were edited by hand), not_found or failed.
+// @Produce json
+// @Success 200 {object} model.EnrichmentRuns
+// @Failure 500 {object} model.ErrRes
+// @Router       /enrichment/runs [get]
+func (h *Handler) listEnrichmentRunsHandler(w http.ResponseWriter, r *http.Request) {
+	runs := model.EnrichmentRuns{Runs: h.service.EnrichmentRuns()}
+
+	err := jsonutil.WriteJSON(w, http.StatusOK, runs, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
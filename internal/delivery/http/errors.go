@@ -0,0 +1,29 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/internal/repository/external"
+	errResponses "effective-mobile-song-library/pkg/errors"
+)
+
+// serverErrorResponse reports an unexpected error, telling a client that went
+// away, an operation that ran out of time and a failing external API apart
+// from server failures.
+func serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(r.Context().Err(), context.Canceled):
+		errResponses.ClientClosedRequestResponse(w, r)
+	case db.IsTimeout(err):
+		errResponses.TimeoutResponse(w, r, err)
+	case errors.Is(err, external.ErrCircuitOpen):
+		errResponses.ServiceUnavailableResponse(w, r, err)
+	case errors.Is(err, external.ErrNoResponse):
+		errResponses.BadGatewayResponse(w, r, err)
+	default:
+		errResponses.ServerErrorResponse(w, r, err)
+	}
+}
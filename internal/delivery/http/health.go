@@ -0,0 +1,33 @@
+package http
+
+import (
+	"net/http"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/pkg/breaker"
+	"effective-mobile-song-library/pkg/jsonutil"
+)
+
+// @Summary healthcheck
+// @Tags health
+// @Description report whether the API is available, and the state of the circuit breakers guarding the providers of song details: closed, open (requests to the provider fail fast) or half-open (it is being tried again). The status is degraded while a breaker is not closed. The cache counters tell how many lookups were answered from the cache (negative hits being songs the provider does not know) and how many reached the provider.
+// @Produce json
+// @Success 200 {object} model.Health
+// @Failure 500 {object} model.ErrRes
+// @Router       /healthcheck [get]
+func (h *Handler) healthcheckHandler(w http.ResponseWriter, r *http.Request) {
+	health := model.Health{
+		Status:    "available",
+		Providers: h.service.ProvidersHealth(),
+	}
+	for _, p := range health.Providers {
+		if p.State.State != breaker.StateClosed {
+			health.Status = "degraded"
+		}
+	}
+
+	err := jsonutil.WriteJSON(w, http.StatusOK, health, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
@@ -1,12 +1,15 @@
 package http
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 
+	"effective-mobile-song-library/internal/model"
 	"effective-mobile-song-library/pkg/validator"
 )
 
@@ -48,13 +51,169 @@ func readUint(qs url.Values, key string, defaultValue uint, v *validator.Validat
 	return uint(i)
 }
 
+func readFloat(qs url.Values, key string, defaultValue float64, v *validator.Validator) float64 {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return defaultValue
+	}
+	return f
+}
+
+func readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
 func readIDFromPath(r *http.Request, v *validator.Validator) uint64 {
+	return readIDParamFromPath(r, "id", v)
+}
+
+func readIDParamFromPath(r *http.Request, key string, v *validator.Validator) uint64 {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseUint(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseUint(params.ByName(key), 10, 64)
 	if err != nil || id < 1 {
-		v.AddError("id", "invalid id parameter")
+		v.AddError(key, "invalid "+key+" parameter")
 		return 0
 	}
 	return id
 }
+
+func readSongFilters(qs url.Values, v *validator.Validator) model.SongFilters {
+	var filters model.SongFilters
+
+	filters.Group = readString(qs, "group", "")
+	filters.Song = readString(qs, "song", "")
+	filters.ReleaseDate = readString(qs, "releaseDate", "")
+	filters.ReleasedFrom = readString(qs, "releasedFrom", "")
+	filters.ReleasedTo = readString(qs, "releasedTo", "")
+	filters.Text = readString(qs, "text", "")
+	filters.Link = readString(qs, "link", "")
+	filters.Album = readString(qs, "album", "")
+	filters.Match = readString(qs, "match", model.MatchExact)
+	if qs.Get("threshold") != "" {
+		// an explicit zero is kept apart from the default
+		threshold := readFloat(qs, "threshold", 0, v)
+		filters.Threshold = &threshold
+	}
+	filters.Sort = readString(qs, "sort", "")
+
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	// an empty after= requests the first page in keyset mode
+	filters.Keyset = qs.Has("after") || qs.Has("before")
+	filters.After = readString(qs, "after", "")
+	filters.Before = readString(qs, "before", "")
+
+	return filters
+}
+
+// songList picks the representation of a song listing: the songs wrapped
+// with the pagination metadata or cursors, or the bare array for clients
+// requesting envelope=false.
+func songList(songs *model.Songs, envelope bool) any {
+	if !envelope {
+		return songs.Songs
+	}
+	return songs
+}
+
+// songListLinks builds the RFC 8288 Link header pointing at the neighbouring
+// pages of a song listing, keeping the rest of the request query.
+func songListLinks(r *http.Request, filters model.SongFilters, songs *model.Songs) string {
+	var links []string
+
+	link := func(rel string, set map[string]string) {
+		qs := r.URL.Query()
+		for _, key := range []string{"page", "after", "before"} {
+			qs.Del(key)
+		}
+		for key, value := range set {
+			qs.Set(key, value)
+		}
+		u := url.URL{Path: r.URL.Path, RawQuery: qs.Encode()}
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel))
+	}
+
+	if filters.Keyset {
+		link("first", map[string]string{"after": ""})
+		if songs.Cursors != nil && songs.Cursors.Prev != "" {
+			link("prev", map[string]string{"before": songs.Cursors.Prev})
+		}
+		if songs.Cursors != nil && songs.Cursors.Next != "" {
+			link("next", map[string]string{"after": songs.Cursors.Next})
+		}
+		return strings.Join(links, ", ")
+	}
+
+	if songs.Metadata == nil || songs.Metadata.TotalRecords == 0 {
+		return ""
+	}
+	m := songs.Metadata
+	page := func(n uint) map[string]string {
+		return map[string]string{"page": strconv.FormatUint(uint64(n), 10)}
+	}
+
+	link("first", page(m.FirstPage))
+	if m.CurrentPage > m.FirstPage {
+		link("prev", page(m.CurrentPage-1))
+	}
+	if m.CurrentPage < m.LastPage {
+		link("next", page(m.CurrentPage+1))
+	}
+	link("last", page(m.LastPage))
+
+	return strings.Join(links, ", ")
+}
+
+// versionETag formats the record version as a strong entity tag.
+func versionETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// checkIfMatch evaluates the If-Match precondition against the current version
+// of the record. It reports whether the header was sent and whether it matches.
+func checkIfMatch(r *http.Request, version uint) (present bool, ok bool) {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return false, true
+	}
+
+	etag := versionETag(version)
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			// weak tags never match, If-Match uses the strong comparison
+			if tag == "*" || tag == etag {
+				return true, true
+			}
+		}
+	}
+	return true, false
+}
+
+// readEditor returns the name of the person making the change,
+// sent in the X-Editor header. It is recorded in the song revisions.
+func readEditor(r *http.Request, v *validator.Validator) string {
+	editor := strings.TrimSpace(r.Header.Get("X-Editor"))
+	v.Check(len(editor) <= 100, "editor", "must not be more than 100 bytes long")
+	return editor
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// enqueueSong answers POST /songs?async=true with the job adding the song.
+func (h *Handler) enqueueSong(w http.ResponseWriter, r *http.Request, group string, song string, editor string, onConflict string) {
+	job, err := h.service.EnqueueInsert(r.Context(), group, song, editor, onConflict)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("enqueued", map[string]any{
+		"job": job,
+	})
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/jobs/%d", job.ID))
+
+	err = jsonutil.WriteJSON(w, http.StatusAccepted, job, headers)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary get job
+// @Tags jobs
+// @Description get the state of a song added in the background with POST /songs?async=true: pending, running, done or failed. A done job has a result (created, updated, duplicate or not_found) and the song, if any.
+// @Accept json
+// @Produce json
+// @Param  id path uint true "job ID"
+// @Success 200 {object} model.JobOut
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /jobs/{id} [get]
+func (h *Handler) showJobHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+	})
+
+	job, err := h.service.GetJob(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, job, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,427 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"effective-mobile-song-library/internal/delivery"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// @Summary list playlists
+// @Tags playlists
+// @Description listing playlists
+// @Accept json
+// @Produce json
+// @Param  name   query string  false  "search by a part of playlist's name"
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Success 200 {array} model.PlaylistOut
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists [get]
+func (h *Handler) listPlaylistsHandler(w http.ResponseWriter, r *http.Request) {
+	var filters model.PlaylistFilters
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters.Name = readString(qs, "name", "")
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	if delivery.ValidatePlaylistFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	playlists, err := h.service.GetPlaylists(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, playlists, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary get playlist
+// @Tags playlists
+// @Description get playlist with its entries in order, each with a summary of its song
+// @Accept json
+// @Produce json
+// @Param  id path uint true "playlist id"
+// @Success 200 {object} model.Playlist
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists/{id} [get]
+func (h *Handler) showPlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	playlist, err := h.service.GetPlaylist(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, playlist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary add playlist
+// @Tags playlists
+// @Description add an empty playlist
+// @Accept json
+// @Produce json
+// @Param  input body   model.PlaylistInput   true  "playlist info struct"
+// @Success 201 {object} model.Playlist
+// @Failure 400 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists [post]
+func (h *Handler) addPlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	var input model.PlaylistInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"input":  input,
+	})
+
+	playlist := &model.Playlist{Entries: []model.PlaylistEntry{}}
+	if input.Name != nil {
+		playlist.Name = *input.Name
+	}
+	if input.Description != nil {
+		playlist.Description = *input.Description
+	}
+
+	// validate
+	v := validator.New()
+	if delivery.ValidatePlaylist(v, playlist); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.service.InsertPlaylist(r.Context(), playlist)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusCreated, playlist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary update playlist
+// @Tags playlists
+// @Description update playlist name and description by ID
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "playlist ID"
+// @Param  input body   model.PlaylistInput   true  "playlist info struct"
+// @Success 200 {object} model.Playlist
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists/{id} [patch]
+func (h *Handler) updatePlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	playlist, err := h.service.GetPlaylist(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input model.PlaylistInput
+
+	err = jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"input":  input,
+	})
+
+	if input.Name != nil {
+		playlist.Name = *input.Name
+	}
+	if input.Description != nil {
+		playlist.Description = *input.Description
+	}
+
+	// validate
+	if delivery.ValidatePlaylist(v, playlist); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.service.UpdatePlaylist(r.Context(), playlist)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, playlist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary delete playlist
+// @Tags playlists
+// @Description delete playlist, the songs in it are kept
+// @Accept json
+// @Produce json
+// @Param  id   path      uint  true  "playlist ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists/{id} [delete]
+func (h *Handler) deletePlaylistHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+	})
+
+	err := h.service.DeletePlaylist(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, map[string]string{"message": "playlist successfully deleted"}, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary add song to playlist
+// @Tags playlists
+// @Description add a song to the playlist at the given position (appended if omitted), the following entries are shifted. A song may be added more than once, every entry having its own ID.
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "playlist ID"
+// @Param  input body   model.PlaylistEntryInput   true  "entry struct"
+// @Success 200 {object} model.Playlist
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists/{id}/entries [post]
+func (h *Handler) addPlaylistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	var input model.PlaylistEntryInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"input":  input,
+	})
+
+	if delivery.ValidatePlaylistEntry(v, input); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	playlist, err := h.service.AddPlaylistEntry(r.Context(), id, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, playlist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary move playlist entry
+// @Tags playlists
+// @Description move an entry of the playlist to the given position (the end if it is past it), the entries in between are shifted
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "playlist ID"
+// @Param  entryId   path    uint  true  "entry ID"
+// @Param  input body   model.PlaylistMoveInput   true  "new position"
+// @Success 200 {object} model.Playlist
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists/{id}/entries/{entryId} [patch]
+func (h *Handler) movePlaylistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	entryID := readIDParamFromPath(r, "entryId", v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	var input model.PlaylistMoveInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":   r.Method,
+		"url":      r.URL.String(),
+		"id":       id,
+		"entry_id": entryID,
+		"input":    input,
+	})
+
+	if delivery.ValidatePlaylistMove(v, input); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	playlist, err := h.service.MovePlaylistEntry(r.Context(), id, entryID, input.Position)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, playlist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary remove playlist entry
+// @Tags playlists
+// @Description remove an entry from the playlist, the following entries are shifted up
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "playlist ID"
+// @Param  entryId   path    uint  true  "entry ID"
+// @Success 200 {object} model.Playlist
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /playlists/{id}/entries/{entryId} [delete]
+func (h *Handler) removePlaylistEntryHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	entryID := readIDParamFromPath(r, "entryId", v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":   r.Method,
+		"url":      r.URL.String(),
+		"id":       id,
+		"entry_id": entryID,
+	})
+
+	playlist, err := h.service.RemovePlaylistEntry(r.Context(), id, entryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, playlist, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
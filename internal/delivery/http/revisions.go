@@ -0,0 +1,265 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"effective-mobile-song-library/internal/delivery"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// @Summary list revisions
+// @Tags revisions
+// @Description listing song revisions, newest first
+// @Accept json
+// @Produce json
+// @Param  id path uint true "song id"
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Success 200 {array} model.SongRevisionOut
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/revisions [get]
+func (h *Handler) listSongRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	var filters model.SongRevisionFilters
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters.SongID = readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	if delivery.ValidateSongRevisionFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	_, err := h.service.Get(r.Context(), filters.SongID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revisions, err := h.service.GetRevisions(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, revisions, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary get revision
+// @Tags revisions
+// @Description get song data as of the revision
+// @Accept json
+// @Produce json
+// @Param  id path uint true "song id"
+// @Param  rev path uint true "revision number"
+// @Success 200 {object} model.SongRevision
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/revisions/{rev} [get]
+func (h *Handler) showSongRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	rev := uint(readIDParamFromPath(r, "rev", v))
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	revision, err := h.service.GetRevision(r.Context(), id, rev)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, revision, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary diff revisions
+// @Tags revisions
+// @Description verse-level diff between two song revisions, by default between the revision and the previous one
+// @Accept json
+// @Produce json
+// @Param  id path uint true "song id"
+// @Param  rev path uint true "revision number to compare to"
+// @Param  from query uint false "revision number to compare from, default rev-1"
+// @Success 200 {object} model.SongDiff
+// @Failure 404 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/revisions/{rev}/diff [get]
+func (h *Handler) diffSongRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	rev := uint(readIDParamFromPath(r, "rev", v))
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	from := readUint(r.URL.Query(), "from", rev-1, v)
+	if !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"from":   from,
+		"to":     rev,
+	})
+
+	diff, err := h.service.Diff(r.Context(), id, from, rev)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, diff, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary revert to revision
+// @Tags revisions
+// @Description restore the song data of an older revision, it is recorded as a new revision
+// @Accept json
+// @Produce json
+// @Param  id path uint true "song id"
+// @Param  rev path uint true "revision number to revert to"
+// @Param  If-Match   header    string  false  "ETag of the song version being updated"
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Success 200 {object} model.SongInfo
+// @Header 200 {string} ETag "new song version"
+// @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/revisions/{rev}/revert [post]
+func (h *Handler) revertSongRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	rev := uint(readIDParamFromPath(r, "rev", v))
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":   r.Method,
+		"url":      r.URL.String(),
+		"id":       id,
+		"revision": rev,
+	})
+
+	song, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	ifMatch, ok := checkIfMatch(r, song.Version)
+	if !ok {
+		errResponses.PreconditionFailedResponse(w, r)
+		return
+	}
+
+	revision, err := h.service.GetRevision(r.Context(), id, rev)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	song.Group = revision.Group
+	song.Song = revision.Song
+	song.ReleaseDate = revision.ReleaseDate
+	song.Text = revision.Text
+	song.Link = revision.Link
+	song.Language = revision.Language
+
+	// validate
+	editor := readEditor(r, v)
+	if model.ValidateSongInfo(v, song); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.service.Update(r.Context(), song, model.SongChange{Editor: editor, RevertedFrom: rev})
+	if err != nil {
+		var duplicate *db.DuplicateSongError
+		switch {
+		case errors.As(err, &duplicate):
+			errResponses.DuplicateSongResponse(w, r, duplicate.ID)
+		case errors.Is(err, db.ErrEditConflict) && ifMatch:
+			errResponses.PreconditionFailedResponse(w, r)
+		case errors.Is(err, db.ErrEditConflict):
+			errResponses.EditConflictResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(song.Version))
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, song, headers)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
@@ -19,12 +19,73 @@ func (h *Handler) Routes() http.Handler {
 	router.MethodNotAllowed = http.HandlerFunc(responses.MethodNotAllowedResponse)
 
 	router.HandlerFunc(http.MethodGet, "/songs", h.listSongsHandler)
+	router.HandlerFunc(http.MethodGet, "/songs/:id", h.showSongOrSearchHandler)
 	router.HandlerFunc(http.MethodGet, "/songs/:id/text", h.listSongTextHandler)
 	router.HandlerFunc(http.MethodPost, "/songs", h.addSongInfoHandler)
+	router.HandlerFunc(http.MethodPost, "/songs/:id", h.addSongsBatchOrNotAllowedHandler)
 	router.HandlerFunc(http.MethodPatch, "/songs/:id", h.updateSongInfoHandler)
 	router.HandlerFunc(http.MethodDelete, "/songs/:id", h.deleteSongInfoHandler)
 
+	router.HandlerFunc(http.MethodPost, "/songs/:id/verses", h.addVerseHandler)
+	router.HandlerFunc(http.MethodPut, "/songs/:id/verses/:n", h.updateVerseHandler)
+	router.HandlerFunc(http.MethodDelete, "/songs/:id/verses/:n", h.deleteVerseHandler)
+	router.HandlerFunc(http.MethodPost, "/songs/:id/verses/reorder", h.reorderVersesHandler)
+
+	router.HandlerFunc(http.MethodPost, "/songs/:id/restore", h.restoreSongHandler)
+	router.HandlerFunc(http.MethodGet, "/trash", h.listTrashHandler)
+	router.HandlerFunc(http.MethodDelete, "/trash", h.purgeTrashHandler)
+
+	router.HandlerFunc(http.MethodGet, "/songs/:id/revisions", h.listSongRevisionsHandler)
+	router.HandlerFunc(http.MethodGet, "/songs/:id/revisions/:rev", h.showSongRevisionHandler)
+	router.HandlerFunc(http.MethodGet, "/songs/:id/revisions/:rev/diff", h.diffSongRevisionsHandler)
+	router.HandlerFunc(http.MethodPost, "/songs/:id/revisions/:rev/revert", h.revertSongRevisionHandler)
+
+	router.HandlerFunc(http.MethodGet, "/jobs/:id", h.showJobHandler)
+	router.HandlerFunc(http.MethodGet, "/enrichment/runs", h.listEnrichmentRunsHandler)
+
+	router.HandlerFunc(http.MethodGet, "/groups", h.listArtistsHandler)
+	router.HandlerFunc(http.MethodGet, "/groups/:id", h.showArtistHandler)
+	router.HandlerFunc(http.MethodGet, "/groups/:id/songs", h.listArtistSongsHandler)
+
+	router.HandlerFunc(http.MethodGet, "/albums", h.listAlbumsHandler)
+	router.HandlerFunc(http.MethodGet, "/albums/:id", h.showAlbumHandler)
+	router.HandlerFunc(http.MethodPost, "/albums", h.addAlbumHandler)
+	router.HandlerFunc(http.MethodPatch, "/albums/:id", h.updateAlbumHandler)
+	router.HandlerFunc(http.MethodDelete, "/albums/:id", h.deleteAlbumHandler)
+	router.HandlerFunc(http.MethodPut, "/albums/:id/tracks", h.setAlbumTrackHandler)
+	router.HandlerFunc(http.MethodDelete, "/albums/:id/tracks/:songId", h.removeAlbumTrackHandler)
+
+	router.HandlerFunc(http.MethodGet, "/playlists", h.listPlaylistsHandler)
+	router.HandlerFunc(http.MethodGet, "/playlists/:id", h.showPlaylistHandler)
+	router.HandlerFunc(http.MethodPost, "/playlists", h.addPlaylistHandler)
+	router.HandlerFunc(http.MethodPatch, "/playlists/:id", h.updatePlaylistHandler)
+	router.HandlerFunc(http.MethodDelete, "/playlists/:id", h.deletePlaylistHandler)
+	router.HandlerFunc(http.MethodPost, "/playlists/:id/entries", h.addPlaylistEntryHandler)
+	router.HandlerFunc(http.MethodPatch, "/playlists/:id/entries/:entryId", h.movePlaylistEntryHandler)
+	router.HandlerFunc(http.MethodDelete, "/playlists/:id/entries/:entryId", h.removePlaylistEntryHandler)
+
+	router.HandlerFunc(http.MethodGet, "/healthcheck", h.healthcheckHandler)
 	router.HandlerFunc(http.MethodGet, "/swagger/:any", httpSwagger.WrapHandler)
 
 	return router
 }
+
+// showSongOrSearchHandler serves both GET /songs/search and GET /songs/:id,
+// because httprouter does not allow a static segment next to a wildcard one.
+func (h *Handler) showSongOrSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if httprouter.ParamsFromContext(r.Context()).ByName("id") == "search" {
+		h.searchSongsHandler(w, r)
+		return
+	}
+	h.showSongHandler(w, r)
+}
+
+// addSongsBatchOrNotAllowedHandler serves POST /songs/batch, any other song
+// does not accept POST.
+func (h *Handler) addSongsBatchOrNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	if httprouter.ParamsFromContext(r.Context()).ByName("id") == "batch" {
+		h.addSongsBatchHandler(w, r)
+		return
+	}
+	responses.MethodNotAllowedResponse(w, r)
+}
@@ -1,12 +1,14 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
 	"effective-mobile-song-library/internal/delivery"
 	"effective-mobile-song-library/internal/model"
 	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/internal/repository/external"
 	errResponses "effective-mobile-song-library/pkg/errors"
 	"effective-mobile-song-library/pkg/jsonutil"
 	"effective-mobile-song-library/pkg/logger"
@@ -14,12 +16,48 @@ import (
 )
 
 type SongLibraryService interface {
-	Get(id uint64) (*model.SongInfo, error)
-	GetAll(model.SongFilters) ([]*model.SongOut, error)
-	GetText(model.SongTextFilters) (*string, error)
-	Insert(group string, song string) (*model.SongInfo, error)
-	Update(songs *model.SongInfo) error
-	Delete(id uint64) error
+	Get(ctx context.Context, id uint64) (*model.SongInfo, error)
+	GetAll(ctx context.Context, filters model.SongFilters) (*model.Songs, error)
+	GetText(ctx context.Context, filters model.SongTextFilters) (*string, error)
+	GetVerses(ctx context.Context, filters model.SongTextFilters) ([]model.Verse, error)
+	Search(ctx context.Context, filters model.SongSearchFilters) ([]*model.SongSearchResult, error)
+	Insert(ctx context.Context, group string, song string, editor string, onConflict string) (*model.SongInfo, error)
+	Create(ctx context.Context, song *model.SongInfo, editor string, onConflict string) (*model.SongInfo, error)
+	InsertBatch(ctx context.Context, input model.SongsInput, editor string, onConflict string) []model.SongBatchResult
+	EnqueueInsert(ctx context.Context, group string, song string, editor string, onConflict string) (*model.Job, error)
+	GetJob(ctx context.Context, id uint64) (*model.JobOut, error)
+	ProvidersHealth() []model.ProviderHealth
+	EnrichmentRuns() []*model.EnrichmentRun
+	Update(ctx context.Context, song *model.SongInfo, change model.SongChange) error
+	Delete(ctx context.Context, id uint64, version uint) error
+
+	GetArtist(ctx context.Context, id uint64) (*model.Artist, error)
+	GetArtists(ctx context.Context, filters model.ArtistFilters) ([]*model.Artist, error)
+
+	GetAlbum(ctx context.Context, id uint64) (*model.Album, error)
+	GetAlbums(ctx context.Context, filters model.AlbumFilters) ([]*model.AlbumOut, error)
+	InsertAlbum(ctx context.Context, album *model.Album) error
+	UpdateAlbum(ctx context.Context, album *model.Album) error
+	DeleteAlbum(ctx context.Context, id uint64) error
+	SetAlbumTrack(ctx context.Context, albumID uint64, track model.AlbumTrackInput) (*model.Album, error)
+	RemoveAlbumTrack(ctx context.Context, albumID uint64, songID uint64) (*model.Album, error)
+
+	GetPlaylist(ctx context.Context, id uint64) (*model.Playlist, error)
+	GetPlaylists(ctx context.Context, filters model.PlaylistFilters) ([]*model.PlaylistOut, error)
+	InsertPlaylist(ctx context.Context, playlist *model.Playlist) error
+	UpdatePlaylist(ctx context.Context, playlist *model.Playlist) error
+	DeletePlaylist(ctx context.Context, id uint64) error
+	AddPlaylistEntry(ctx context.Context, playlistID uint64, entry model.PlaylistEntryInput) (*model.Playlist, error)
+	MovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64, position uint) (*model.Playlist, error)
+	RemovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64) (*model.Playlist, error)
+
+	GetRevisions(ctx context.Context, filters model.SongRevisionFilters) ([]*model.SongRevisionOut, error)
+	GetRevision(ctx context.Context, songID uint64, revision uint) (*model.SongRevision, error)
+	Diff(ctx context.Context, songID uint64, from uint, to uint) (*model.SongDiff, error)
+
+	GetTrash(ctx context.Context, filters model.TrashFilters) ([]*model.DeletedSongOut, error)
+	Restore(ctx context.Context, id uint64) (*model.SongInfo, error)
+	Purge(ctx context.Context) (int64, error)
 }
 
 // @Summary list
@@ -30,27 +68,28 @@ type SongLibraryService interface {
 // @Param  group   query string  false  "name search by group"
 // @Param  song   query string  false  "name search by song"
 // @Param  releaseDate   query string  false  "search by release date (YYYY, MM.YYYY or DD.MM.YYYY)"
+// @Param  releasedFrom   query string  false  "released on or after the date (YYYY, MM.YYYY or DD.MM.YYYY)"
+// @Param  releasedTo   query string  false  "released on or before the date (YYYY, MM.YYYY or DD.MM.YYYY)"
 // @Param  text   query string  false  "search by a part of song's text"
 // @Param  link   query string  false  "match link"
+// @Param  album   query string  false  "search by album title"
+// @Param  match   query string  false  "group and song matching: exact (default) or fuzzy (typo-tolerant, returns similarity score)"
+// @Param  threshold   query number  false  "similarity threshold for fuzzy matching between 0 and 1, default from config"
+// @Param  sort   query string  false  "comma-separated sort keys: id, group, song, releaseDate; prefix with - for descending, e.g. -releaseDate,group,song; default id"
 // @Param  page   query uint  false  "page number, default 1"
 // @Param  pageSize   query uint  false  "page size, default 10"
+// @Param  after   query string  false  "keyset pagination: cursor of the row the page starts after, empty for the first page; the response then holds the cursors of the neighbouring pages instead of the metadata"
+// @Param  before   query string  false  "keyset pagination: cursor of the row the page ends before"
+// @Param  envelope   query bool  false  "wrap the songs with the pagination metadata, default true; false returns the bare array"
 // @Success 200 {object} model.Songs
+// @Header 200 {string} Link "RFC 8288 links to the first, prev, next and last pages"
 // @Failure 422 {object} model.ErrRes
 // @Failure 500 {object} model.ErrRes
 // @Router       /songs [get]
 func (h *Handler) listSongsHandler(w http.ResponseWriter, r *http.Request) {
-	var filters model.SongFilters
-	qs := r.URL.Query()
 	v := validator.New()
-
-	filters.Group = readString(qs, "group", "")
-	filters.Song = readString(qs, "song", "")
-	filters.ReleaseDate = readString(qs, "releaseDate", "")
-	filters.Text = readString(qs, "text", "")
-	filters.Link = readString(qs, "link", "")
-
-	filters.Page = readUint(qs, "page", 1, v)
-	filters.PageSize = readUint(qs, "pageSize", 10, v)
+	filters := readSongFilters(r.URL.Query(), v)
+	envelope := readBool(r.URL.Query(), "envelope", true, v)
 
 	if delivery.ValidateSongFilters(v, filters); !v.Valid() {
 		errResponses.FailedValidationResponse(w, r, v.Errors)
@@ -63,21 +102,116 @@ func (h *Handler) listSongsHandler(w http.ResponseWriter, r *http.Request) {
 		"filters": filters,
 	})
 
-	songs, err := h.service.GetAll(filters)
+	songs, err := h.service.GetAll(r.Context(), filters)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, db.ErrInvalidCursor):
+			invalidCursorResponse(w, r, filters)
+		default:
+			serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	logger.PrintDebug("", map[string]any{
 		"url":               r.URL.String(),
-		"number of records": len(songs),
-		"songs list":        songs,
+		"number of records": len(songs.Songs),
+		"songs list":        songs.Songs,
 	})
 	// Send a JSON response containing the song info.
-	err = jsonutil.WriteJSON(w, http.StatusOK, songs, nil)
+	headers := make(http.Header)
+	if links := songListLinks(r, filters, songs); links != "" {
+		headers.Set("Link", links)
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, songList(songs, envelope), headers)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary get
+// @Tags songs
+// @Description get song data by ID, the ETag header holds the song version
+// @Accept json
+// @Produce json
+// @Param  id path uint true "song id"
+// @Success 200 {object} model.SongInfo
+// @Header 200 {string} ETag "song version"
+// @Failure 404 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id} [get]
+func (h *Handler) showSongHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	song, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(song.Version))
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, song, headers)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary search
+// @Tags songs
+// @Description ranked full-text search over song titles and lyrics. The snippet is HTML-escaped, with the matches highlighted with <mark>, verses holds the numbers of the matching verses.
+// @Accept json
+// @Produce json
+// @Param  q   query string  true  "search query, supports quoted phrases, OR and -exclusions"
+// @Param  lang   query string  false  "search only songs in the language: simple, english or russian"
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Success 200 {array} model.SongSearchResult
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/search [get]
+func (h *Handler) searchSongsHandler(w http.ResponseWriter, r *http.Request) {
+	var filters model.SongSearchFilters
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters.Query = readString(qs, "q", "")
+	filters.Language = readString(qs, "lang", "")
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	if delivery.ValidateSongSearchFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	results, err := h.service.Search(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, results, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
 	}
 }
 
@@ -88,7 +222,10 @@ func (h *Handler) listSongsHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param  id path uint true "song id"
 // @Param  verse   query uint  false  "verse number, default 0 (display full text)"
+// @Param  format   query string  false  "plain (default) renders the text, typed returns the verses with their types"  Enums(plain, typed)
 // @Success 200 {object} model.SongText
+// @Success 200 {object} model.SongVerses "format=typed without a verse number"
+// @Success 200 {object} model.Verse "format=typed with a verse number"
 // @Failure 400 {object} model.ErrRes
 // @Failure 422 {object} model.ErrRes
 // @Failure 500 {object} model.ErrRes
@@ -105,19 +242,20 @@ func (h *Handler) listSongTextHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch the existing song info from the database
-	song, err := h.service.Get(id)
+	song, err := h.service.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, db.ErrRecordNotFound):
 			errResponses.NotFoundResponse(w, r)
 		default:
-			errResponses.ServerErrorResponse(w, r, err)
+			serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
 	filters.ID = uint64(id)
 	filters.Verse = readUint(qs, "verse", 0, v)
+	filters.Format = readString(qs, "format", model.TextFormatPlain)
 
 	if delivery.ValidateSongTextFilters(v, filters, uint(len(song.Text))); !v.Valid() {
 		errResponses.FailedValidationResponse(w, r, v.Errors)
@@ -130,9 +268,14 @@ func (h *Handler) listSongTextHandler(w http.ResponseWriter, r *http.Request) {
 		"filters": filters,
 	})
 
-	verse, err := h.service.GetText(filters)
+	if filters.Format == model.TextFormatTyped {
+		h.writeVerses(w, r, filters)
+		return
+	}
+
+	verse, err := h.service.GetText(r.Context(), filters)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		serverErrorResponse(w, r, err)
 		return
 	}
 
@@ -144,26 +287,58 @@ func (h *Handler) listSongTextHandler(w http.ResponseWriter, r *http.Request) {
 	// Send a JSON response containing the verse.
 	err = jsonutil.WriteJSON(w, http.StatusOK, verse, nil)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// writeVerses sends the verses of the song with their types, a single verse
+// is sent on its own.
+func (h *Handler) writeVerses(w http.ResponseWriter, r *http.Request, filters model.SongTextFilters) {
+	verses, err := h.service.GetVerses(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"url":    r.URL.String(),
+		"verses": verses,
+	})
+
+	var out any = model.SongVerses{Verses: verses}
+	if filters.Verse != 0 {
+		if len(verses) == 0 {
+			errResponses.NotFoundResponse(w, r)
+			return
+		}
+		out = verses[0]
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, out, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
 	}
 }
 
 // @Summary add
 // @Tags songs
-// @Description add songs info
+// @Description add a song. Given only the group and the song, its details are looked up from the providers, and 422 is returned if none of them knows the song. Given any of its details (releaseDate, text, link, language), the song is added with them as is and the providers are not asked; the release date is then required and the language detected from the lyrics if omitted.
 // @Accept json
 // @Produce json
-// @Param  songs body model.SongsInput  true  "song collection"
-// @Success 200 {object} model.Songs
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Param  onConflict   query    string  false  "what to do if the group already has a song with this title: fail with 409 (default) or update its details"  Enums(fail, update)
+// @Param  async   query    bool  false  "look the song up in the background: respond with 202 and the job to poll at GET /jobs/{id}; not allowed with the details of the song"
+// @Param  song body model.SongInput  true  "group and song to add, with its details to add it without the providers"
+// @Success 200 {object} model.SongInfo
+// @Success 202 {object} model.Job
+// @Header 202 {string} Location "URL of the job"
 // @Failure 400 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
 // @Failure 422 {object} model.ErrRes
 // @Failure 500 {object} model.ErrRes
 // @Router       /songs [post]
 func (h *Handler) addSongInfoHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Group string `json:"group"`
-		Song  string `json:"song"`
-	}
+	var input model.SongInput
 
 	err := jsonutil.ReadJSON(w, r, &input)
 	if err != nil {
@@ -179,33 +354,119 @@ func (h *Handler) addSongInfoHandler(w http.ResponseWriter, r *http.Request) {
 
 	// validate
 	v := validator.New()
-	if delivery.ValidateSongInput(v, input.Group, input.Song); !v.Valid() {
+	editor := readEditor(r, v)
+	onConflict := readString(r.URL.Query(), "onConflict", model.OnConflictFail)
+	async := readBool(r.URL.Query(), "async", false, v)
+	song := input.SongInfo()
+	delivery.ValidateSongInput(v, song.Group, song.Song, onConflict)
+	if input.HasDetails() {
+		v.Check(!async, "async", "cannot be used with the details of the song")
+		model.ValidateNewSongInfo(v, song)
+	}
+	if !v.Valid() {
 		errResponses.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	song, err := h.service.Insert(input.Group, input.Song)
+	if async {
+		h.enqueueSong(w, r, song.Group, song.Song, editor, onConflict)
+		return
+	}
+
+	if input.HasDetails() {
+		song, err = h.service.Create(r.Context(), song, editor, onConflict)
+	} else {
+		song, err = h.service.Insert(r.Context(), song.Group, song.Song, editor, onConflict)
+	}
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		var duplicate *db.DuplicateSongError
+		switch {
+		case errors.As(err, &duplicate):
+			errResponses.DuplicateSongResponse(w, r, duplicate.ID)
+		case errors.Is(err, db.ErrEditConflict):
+			errResponses.EditConflictResponse(w, r)
+		case errors.Is(err, external.ErrUnknownSong):
+			errResponses.FailedValidationResponse(w, r, map[string]string{
+				"song": "is not known to the providers, send its details to add it without them",
+			})
+		default:
+			serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	err = jsonutil.WriteJSON(w, http.StatusOK, song, nil)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary add batch
+// @Tags songs
+// @Description add many songs at once, their details are fetched from the external API a few at a time. Every song gets its own result: created, updated (with onConflict=update), duplicate, not_found (unknown to the external API) or failed; a failed song does not stop the others.
+// @Accept json
+// @Produce json
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Param  onConflict   query    string  false  "what to do if the group already has a song with this title: report a duplicate (default) or update its details from the external API"  Enums(fail, update)
+// @Param  songs body model.SongsInput  true  "groups and songs with the same index make a pair, up to 100 songs"
+// @Success 200 {object} model.SongBatch
+// @Failure 400 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/batch [post]
+func (h *Handler) addSongsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var input model.SongsInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"input":  input,
+	})
+
+	// validate
+	v := validator.New()
+	editor := readEditor(r, v)
+	onConflict := readString(r.URL.Query(), "onConflict", model.OnConflictFail)
+	if delivery.ValidateSongsInput(v, input, onConflict); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results := h.service.InsertBatch(r.Context(), input, editor, onConflict)
+
+	// the songs are not worth reporting to a client that went away
+	if err := r.Context().Err(); err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, model.SongBatch{Results: results}, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
 	}
 }
 
 // @Summary update
 // @Tags songs
-// @Description update song data by ID
+// @Description update song data by ID. Send the ETag of the fetched song in If-Match to make sure it has not been changed since.
 // @Accept json
 // @Produce json
 // @Param  id   path    uint  true  "song ID"
+// @Param  If-Match   header    string  false  "ETag of the song version being updated"
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
 // @Param  input body   model.SongInput   true  "song info struct"
 // @Success 200 {object} model.SongInfo
+// @Header 200 {string} ETag "new song version"
 // @Failure 400 {object} model.ErrRes
 // @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
 // @Failure 422 {object} model.ErrRes
 // @Failure 500 {object} model.ErrRes
 // @Router       /songs/{id} [patch]
@@ -218,17 +479,23 @@ func (h *Handler) updateSongInfoHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Fetch the existing song info from the database
-	song, err := h.service.Get(id)
+	song, err := h.service.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, db.ErrRecordNotFound):
 			errResponses.NotFoundResponse(w, r)
 		default:
-			errResponses.ServerErrorResponse(w, r, err)
+			serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
+	ifMatch, ok := checkIfMatch(r, song.Version)
+	if !ok {
+		errResponses.PreconditionFailedResponse(w, r)
+		return
+	}
+
 	// Declare an input struct to hold the expected data from the client.
 	var input model.SongInput
 
@@ -260,17 +527,31 @@ func (h *Handler) updateSongInfoHandler(w http.ResponseWriter, r *http.Request)
 	if input.Link != nil {
 		song.Link = *input.Link
 	}
+	if input.Language != nil {
+		song.Language = *input.Language
+	}
 
 	// validate
 	v = validator.New()
-	if delivery.ValidateSongInfo(v, song); !v.Valid() {
+	editor := readEditor(r, v)
+	if model.ValidateSongInfo(v, song); !v.Valid() {
 		errResponses.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = h.service.Update(song)
+	err = h.service.Update(r.Context(), song, model.SongChange{Editor: editor})
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		var duplicate *db.DuplicateSongError
+		switch {
+		case errors.As(err, &duplicate):
+			errResponses.DuplicateSongResponse(w, r, duplicate.ID)
+		case errors.Is(err, db.ErrEditConflict) && ifMatch:
+			errResponses.PreconditionFailedResponse(w, r)
+		case errors.Is(err, db.ErrEditConflict):
+			errResponses.EditConflictResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -278,20 +559,25 @@ func (h *Handler) updateSongInfoHandler(w http.ResponseWriter, r *http.Request)
 		"song": song,
 	})
 
-	err = jsonutil.WriteJSON(w, http.StatusOK, song, nil)
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(song.Version))
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, song, headers)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		serverErrorResponse(w, r, err)
 	}
 }
 
 // @Summary delete
 // @Tags songs
-// @Description delete song data
+// @Description move song to the trash, it can be restored until the trash is purged. The song is removed from all playlists, restoring it does not put it back. Send the ETag of the fetched song in If-Match to make sure it has not been changed since.
 // @Accept json
 // @Produce json
 // @Param  id   path      uint  true  "song ID"
+// @Param  If-Match   header    string  false  "ETag of the song version being deleted"
 // @Success 200 {object} model.SongInfo
 // @Failure 404 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
 // @Failure 500 {object} model.ErrRes
 // @Router       /songs/{id} [delete]
 func (h *Handler) deleteSongInfoHandler(w http.ResponseWriter, r *http.Request) {
@@ -308,19 +594,51 @@ func (h *Handler) deleteSongInfoHandler(w http.ResponseWriter, r *http.Request)
 		"id":     id,
 	})
 
-	err := h.service.Delete(id)
+	// Without If-Match the song is deleted unconditionally.
+	var version uint
+	if len(r.Header.Values("If-Match")) > 0 {
+		song, err := h.service.Get(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, db.ErrRecordNotFound):
+				errResponses.NotFoundResponse(w, r)
+			default:
+				serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if _, ok := checkIfMatch(r, song.Version); !ok {
+			errResponses.PreconditionFailedResponse(w, r)
+			return
+		}
+		version = song.Version
+	}
+
+	err := h.service.Delete(r.Context(), id, version)
 	if err != nil {
 		switch {
 		case errors.Is(err, db.ErrRecordNotFound):
 			errResponses.NotFoundResponse(w, r)
+		case errors.Is(err, db.ErrEditConflict):
+			errResponses.PreconditionFailedResponse(w, r)
 		default:
-			errResponses.ServerErrorResponse(w, r, err)
+			serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = jsonutil.WriteJSON(w, http.StatusOK, map[string]string{"message": "song info successfully deleted"}, nil)
+	err = jsonutil.WriteJSON(w, http.StatusOK, map[string]string{"message": "song info successfully moved to the trash"}, nil)
 	if err != nil {
-		errResponses.ServerErrorResponse(w, r, err)
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// invalidCursorResponse reports the cursor parameter rejected by the storage.
+func invalidCursorResponse(w http.ResponseWriter, r *http.Request, filters model.SongFilters) {
+	key := "after"
+	if filters.Before != "" {
+		key = "before"
 	}
+	errResponses.FailedValidationResponse(w, r, map[string]string{key: db.ErrInvalidCursor.Error()})
 }
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"effective-mobile-song-library/internal/delivery"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// @Summary list trash
+// @Tags trash
+// @Description listing deleted songs, most recently deleted first
+// @Accept json
+// @Produce json
+// @Param  page   query uint  false  "page number, default 1"
+// @Param  pageSize   query uint  false  "page size, default 10"
+// @Success 200 {array} model.DeletedSongOut
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /trash [get]
+func (h *Handler) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var filters model.TrashFilters
+	qs := r.URL.Query()
+	v := validator.New()
+
+	filters.Page = readUint(qs, "page", 1, v)
+	filters.PageSize = readUint(qs, "pageSize", 10, v)
+
+	if delivery.ValidateTrashFilters(v, filters); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method":  r.Method,
+		"url":     r.URL.String(),
+		"filters": filters,
+	})
+
+	songs, err := h.service.GetTrash(r.Context(), filters)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, songs, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary purge trash
+// @Tags trash
+// @Description permanently delete the songs that have been in the trash longer than the configured retention period
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]int64
+// @Failure 500 {object} model.ErrRes
+// @Router       /trash [delete]
+func (h *Handler) purgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+	})
+
+	purged, err := h.service.Purge(r.Context())
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	logger.PrintInfo("purged trash", map[string]any{
+		"purged": purged,
+	})
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, map[string]int64{"purged": purged}, nil)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
+
+// @Summary restore
+// @Tags trash
+// @Description restore a deleted song from the trash
+// @Accept json
+// @Produce json
+// @Param  id   path      uint  true  "song ID"
+// @Success 200 {object} model.SongInfo
+// @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/restore [post]
+func (h *Handler) restoreSongHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+	})
+
+	song, err := h.service.Restore(r.Context(), id)
+	if err != nil {
+		var duplicate *db.DuplicateSongError
+		switch {
+		case errors.As(err, &duplicate):
+			errResponses.DuplicateSongResponse(w, r, duplicate.ID)
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(song.Version))
+
+	err = jsonutil.WriteJSON(w, http.StatusOK, song, headers)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
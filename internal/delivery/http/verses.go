@@ -0,0 +1,301 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"effective-mobile-song-library/internal/delivery"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	errResponses "effective-mobile-song-library/pkg/errors"
+	"effective-mobile-song-library/pkg/jsonutil"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// editVerses applies the edit to the verses of the song and saves them as
+// a new revision, honouring If-Match like a PATCH of the whole song. The edit
+// reports a missing verse by returning false and invalid input through v.
+type editVerses func(verses []model.Verse, v *validator.Validator) ([]model.Verse, bool)
+
+// @Summary add verse
+// @Tags verses
+// @Description add a verse to the song's lyrics at the given position (appended if omitted), the following verses are shifted
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "song ID"
+// @Param  If-Match   header    string  false  "ETag of the song version being updated"
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Param  input body   model.VerseInput   true  "verse struct"
+// @Success 201 {object} model.SongVerses
+// @Header 201 {string} ETag "new song version"
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/verses [post]
+func (h *Handler) addVerseHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	input, ok := readVerseInput(w, r)
+	if !ok {
+		return
+	}
+
+	h.updateVerses(w, r, id, http.StatusCreated, func(verses []model.Verse, v *validator.Validator) ([]model.Verse, bool) {
+		if delivery.ValidateNewVerse(v, input, uint(len(verses))); !v.Valid() {
+			return nil, true
+		}
+
+		position := input.Position
+		if position == 0 {
+			position = uint(len(verses)) + 1
+		}
+		verse := model.Verse{Type: input.Type, Label: input.Label, Text: input.Text}
+		return slices.Insert(verses, int(position-1), verse), true
+	})
+}
+
+// @Summary replace verse
+// @Tags verses
+// @Description replace the text, type and label of a verse of the song's lyrics
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "song ID"
+// @Param  n   path    uint  true  "verse number"
+// @Param  If-Match   header    string  false  "ETag of the song version being updated"
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Param  input body   model.VerseInput   true  "verse struct, position is ignored"
+// @Success 200 {object} model.SongVerses
+// @Header 200 {string} ETag "new song version"
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/verses/{n} [put]
+func (h *Handler) updateVerseHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	n := readIDParamFromPath(r, "n", v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	input, ok := readVerseInput(w, r)
+	if !ok {
+		return
+	}
+
+	h.updateVerses(w, r, id, http.StatusOK, func(verses []model.Verse, v *validator.Validator) ([]model.Verse, bool) {
+		if n > uint64(len(verses)) {
+			return nil, false
+		}
+		if delivery.ValidateVerseInput(v, input); !v.Valid() {
+			return nil, true
+		}
+
+		verses[n-1] = model.Verse{Type: input.Type, Label: input.Label, Text: input.Text}
+		return verses, true
+	})
+}
+
+// @Summary delete verse
+// @Tags verses
+// @Description delete a verse of the song's lyrics, the following verses are shifted up
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "song ID"
+// @Param  n   path    uint  true  "verse number"
+// @Param  If-Match   header    string  false  "ETag of the song version being updated"
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Success 200 {object} model.SongVerses
+// @Header 200 {string} ETag "new song version"
+// @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/verses/{n} [delete]
+func (h *Handler) deleteVerseHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	n := readIDParamFromPath(r, "n", v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"verse":  n,
+	})
+
+	h.updateVerses(w, r, id, http.StatusOK, func(verses []model.Verse, v *validator.Validator) ([]model.Verse, bool) {
+		if n > uint64(len(verses)) {
+			return nil, false
+		}
+		return slices.Delete(verses, int(n-1), int(n)), true
+	})
+}
+
+// @Summary reorder verses
+// @Tags verses
+// @Description move the verses of the song's lyrics at once: order lists the current verse numbers in their new order, e.g. [2, 1, 3] swaps the first two verses
+// @Accept json
+// @Produce json
+// @Param  id   path    uint  true  "song ID"
+// @Param  If-Match   header    string  false  "ETag of the song version being updated"
+// @Param  X-Editor   header    string  false  "name of the editor, recorded in the song history"
+// @Param  input body   model.VerseOrderInput   true  "new order of the verses"
+// @Success 200 {object} model.SongVerses
+// @Header 200 {string} ETag "new song version"
+// @Failure 400 {object} model.ErrRes
+// @Failure 404 {object} model.ErrRes
+// @Failure 409 {object} model.ErrRes
+// @Failure 412 {object} model.ErrRes
+// @Failure 422 {object} model.ErrRes
+// @Failure 500 {object} model.ErrRes
+// @Router       /songs/{id}/verses/reorder [post]
+func (h *Handler) reorderVersesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	id := readIDFromPath(r, v)
+	if !v.Valid() {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+
+	var input model.VerseOrderInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"id":     id,
+		"input":  input,
+	})
+
+	h.updateVerses(w, r, id, http.StatusOK, func(verses []model.Verse, v *validator.Validator) ([]model.Verse, bool) {
+		if delivery.ValidateVerseOrder(v, input, uint(len(verses))); !v.Valid() {
+			return nil, true
+		}
+
+		reordered := make([]model.Verse, 0, len(verses))
+		for _, position := range input.Order {
+			reordered = append(reordered, verses[position-1])
+		}
+		return reordered, true
+	})
+}
+
+// readVerseInput reads the verse from the request body, plain verse being
+// the default type. A bad request is answered if the body cannot be read.
+func readVerseInput(w http.ResponseWriter, r *http.Request) (model.VerseInput, bool) {
+	var input model.VerseInput
+
+	err := jsonutil.ReadJSON(w, r, &input)
+	if err != nil {
+		errResponses.BadRequestResponse(w, r, err)
+		return input, false
+	}
+
+	if input.Type == "" {
+		input.Type = model.VerseTypeVerse
+	}
+
+	logger.PrintDebug("", map[string]any{
+		"method": r.Method,
+		"url":    r.URL.String(),
+		"input":  input,
+	})
+
+	return input, true
+}
+
+// updateVerses edits the verses of the song and responds with them.
+func (h *Handler) updateVerses(w http.ResponseWriter, r *http.Request, id uint64, status int, edit editVerses) {
+	song, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			errResponses.NotFoundResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	ifMatch, ok := checkIfMatch(r, song.Version)
+	if !ok {
+		errResponses.PreconditionFailedResponse(w, r)
+		return
+	}
+
+	verses, err := h.service.GetVerses(r.Context(), model.SongTextFilters{ID: id})
+	if err != nil {
+		serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	verses, ok = edit(verses, v)
+	if !ok {
+		errResponses.NotFoundResponse(w, r)
+		return
+	}
+	if !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// validate the song as a whole, like a PATCH with the new text would be
+	song.SetVerses(verses)
+	editor := readEditor(r, v)
+	if model.ValidateSongInfo(v, song); !v.Valid() {
+		errResponses.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.service.Update(r.Context(), song, model.SongChange{Editor: editor})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrEditConflict) && ifMatch:
+			errResponses.PreconditionFailedResponse(w, r)
+		case errors.Is(err, db.ErrEditConflict):
+			errResponses.EditConflictResponse(w, r)
+		default:
+			serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	logger.PrintDebug("updated verses", map[string]any{
+		"id":     id,
+		"verses": song.Verses,
+	})
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(song.Version))
+
+	err = jsonutil.WriteJSON(w, status, model.SongVerses{Verses: song.Verses}, headers)
+	if err != nil {
+		serverErrorResponse(w, r, err)
+	}
+}
@@ -2,44 +2,190 @@ package delivery
 
 import (
 	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/pkg/releasedate"
 	"effective-mobile-song-library/pkg/validator"
 	"fmt"
+	"strings"
 )
 
+// SongSortSafelist holds the keys accepted by the sort parameter of the song listing.
+// The parameter is a comma-separated list of them, e.g. "-releaseDate,group,song".
+var SongSortSafelist = []string{"id", "-id", "group", "-group", "song", "-song", "releaseDate", "-releaseDate"}
+
+func validatePagination(v *validator.Validator, page uint, pageSize uint) {
+	v.Check(page > 0, "page", "must be greater than zero")
+	v.Check(page <= 10_000_000, "page", "must be a maximum of 10 million")
+	v.Check(pageSize > 0, "page_size", "must be greater than zero")
+	v.Check(pageSize <= 100, "page_size", "must be a maximum of 100")
+}
+
 func ValidateSongFilters(v *validator.Validator, f model.SongFilters) {
 	if f.ReleaseDate != "" {
-		v.Check(
-			v.Matches(f.ReleaseDate, validator.ReleaseDateRX) ||
-				v.Matches(f.ReleaseDate, validator.ReleaseYearMonthRX) ||
-				v.Matches(f.ReleaseDate, validator.ReleaseYearRX),
-			"release_date", "invalid format of release date filter",
-		)
+		v.Check(model.MatchesReleaseDate(v, f.ReleaseDate), "release_date", "invalid format of release date filter")
+	}
+	if f.ReleasedFrom != "" {
+		v.Check(model.MatchesReleaseDate(v, f.ReleasedFrom), "released_from", "invalid format of release date filter")
+	}
+	if f.ReleasedTo != "" {
+		v.Check(model.MatchesReleaseDate(v, f.ReleasedTo), "released_to", "invalid format of release date filter")
+	}
+	if v.Valid() && f.ReleasedFrom != "" && f.ReleasedTo != "" {
+		from, _, _ := releasedate.ParsePeriod(f.ReleasedFrom)
+		_, to, _ := releasedate.ParsePeriod(f.ReleasedTo)
+		v.Check(from.Before(to), "released_to", "must not be earlier than released_from")
+	}
+
+	v.Check(validator.PermittedValue(f.Match, model.MatchExact, model.MatchFuzzy), "match", "must be either exact or fuzzy")
+	if f.Threshold != nil {
+		v.Check(*f.Threshold >= 0 && *f.Threshold <= 1, "threshold", "must be between 0 and 1")
+	}
+
+	if f.Sort != "" {
+		v.Check(validSongSort(f.Sort), "sort", "invalid sort value")
+	}
+
+	validatePagination(v, f.Page, f.PageSize)
+
+	if f.Keyset {
+		v.Check(f.After == "" || f.Before == "", "before", "cannot be used together with after")
+		v.Check(len(f.After) <= 1000, "after", "invalid cursor")
+		v.Check(len(f.Before) <= 1000, "before", "invalid cursor")
+		v.Check(f.Page == 1, "page", "cannot be used together with a cursor")
+		// similarity scores are not stable enough to page through
+		v.Check(f.Match != model.MatchFuzzy || f.Sort != "", "sort", "must be set to use cursors with fuzzy matching")
+	}
+}
+
+// validSongSort checks that every sort key is permitted and used only once.
+func validSongSort(sort string) bool {
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(sort, ",") {
+		if !validator.PermittedValue(key, SongSortSafelist...) {
+			return false
+		}
+		key = strings.TrimPrefix(key, "-")
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
 	}
+	return true
+}
+
+func ValidateArtistFilters(v *validator.Validator, f model.ArtistFilters) {
+	validatePagination(v, f.Page, f.PageSize)
+}
+
+func ValidateAlbumFilters(v *validator.Validator, f model.AlbumFilters) {
+	validatePagination(v, f.Page, f.PageSize)
+}
+
+func ValidatePlaylistFilters(v *validator.Validator, f model.PlaylistFilters) {
+	validatePagination(v, f.Page, f.PageSize)
+}
+
+func ValidateSongRevisionFilters(v *validator.Validator, f model.SongRevisionFilters) {
+	validatePagination(v, f.Page, f.PageSize)
+}
 
-	v.Check(f.Page > 0, "page", "must be greater than zero")
-	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
-	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
-	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+func ValidateTrashFilters(v *validator.Validator, f model.TrashFilters) {
+	validatePagination(v, f.Page, f.PageSize)
 }
 
 func ValidateSongTextFilters(v *validator.Validator, f model.SongTextFilters, textLen uint) {
 	v.Check(f.Verse <= textLen, "verse", fmt.Sprintf("must not be greater than total verses: %v", textLen))
 	v.Check(f.Verse <= 10_000_000, "verse", "must be a maximum of 10 million")
+	v.Check(validator.PermittedValue(f.Format, model.TextFormatPlain, model.TextFormatTyped), "format", "must be either plain or typed")
+}
+
+func ValidateVerseInput(v *validator.Validator, verse model.VerseInput) {
+	v.Check(verse.Text != "", "text", "must be provided")
+	v.Check(len(verse.Text) <= model.MaxVerseBytes, "text", "must not be more than 64KB long")
+	v.Check(validator.PermittedValue(verse.Type, model.VerseTypes...), "type", "must be one of: verse, chorus, bridge, intro, outro")
+	v.Check(len(verse.Label) <= 100, "label", "must not be more than 100 bytes long")
+}
+
+// ValidateNewVerse checks a verse added to the lyrics with textLen verses,
+// it can be put at most right after the last one.
+func ValidateNewVerse(v *validator.Validator, verse model.VerseInput, textLen uint) {
+	ValidateVerseInput(v, verse)
+	v.Check(verse.Position <= textLen+1, "position", fmt.Sprintf("must not be greater than total verses + 1: %v", textLen+1))
+}
+
+// ValidateVerseOrder checks that the order lists every position
+// of the lyrics with textLen verses exactly once.
+func ValidateVerseOrder(v *validator.Validator, order model.VerseOrderInput, textLen uint) {
+	seen := make([]bool, textLen+1)
+	for _, position := range order.Order {
+		if position < 1 || position > textLen || seen[position] {
+			v.AddError("order", fmt.Sprintf("must list every verse position from 1 to %v exactly once", textLen))
+			return
+		}
+		seen[position] = true
+	}
+	v.Check(uint(len(order.Order)) == textLen, "order", fmt.Sprintf("must list every verse position from 1 to %v exactly once", textLen))
 }
 
-func ValidateSongInput(v *validator.Validator, group string, song string) {
+func ValidateSongInput(v *validator.Validator, group string, song string, onConflict string) {
 	v.Check(group != "", "group", "must be provided")
 	v.Check(song != "", "song", "must be provided")
+	v.Check(validator.PermittedValue(onConflict, model.OnConflictFail, model.OnConflictUpdate), "on_conflict", "must be either fail or update")
+}
+
+// ValidateSongsInput checks the batch of songs to add, every pair
+// must name both the group and the song.
+func ValidateSongsInput(v *validator.Validator, input model.SongsInput, onConflict string) {
+	v.Check(len(input.Groups) > 0, "groups", "must be provided")
+	v.Check(len(input.Groups) <= 100, "groups", "must not contain more than 100 songs")
+	v.Check(len(input.Songs) == len(input.Groups), "songs", "must contain a song for every group")
+	if !v.Valid() {
+		return
+	}
+
+	for i := range input.Groups {
+		v.Check(input.Groups[i] != "", fmt.Sprintf("groups[%d]", i), "must be provided")
+		v.Check(input.Songs[i] != "", fmt.Sprintf("songs[%d]", i), "must be provided")
+	}
+	v.Check(validator.PermittedValue(onConflict, model.OnConflictFail, model.OnConflictUpdate), "on_conflict", "must be either fail or update")
+}
+
+func ValidateSongSearchFilters(v *validator.Validator, f model.SongSearchFilters) {
+	v.Check(f.Query != "", "q", "must be provided")
+	v.Check(len(f.Query) <= 500, "q", "must not be more than 500 bytes long")
+
+	if f.Language != "" {
+		v.Check(validator.PermittedValue(f.Language, model.SearchLanguages...), "lang", "must be one of: simple, english, russian")
+	}
+
+	validatePagination(v, f.Page, f.PageSize)
 }
 
-func ValidateSongInfo(v *validator.Validator, song *model.SongInfo) {
-	v.Check(song.Group != "", "group", "must be provided")
-	v.Check(song.Song != "", "song", "must be provided")
+func ValidateAlbum(v *validator.Validator, album *model.Album) {
+	v.Check(album.Group != "", "group", "must be provided")
+	v.Check(album.Title != "", "title", "must be provided")
+	v.Check(len(album.Title) <= 500, "title", "must not be more than 500 bytes long")
 
-	v.Check(song.ReleaseDate != "", "release_date", "must be provided")
-	v.Check(v.Matches(song.ReleaseDate, validator.ReleaseDateRX), "release_date", "invalid format of release date")
+	v.Check(album.ReleaseDate != "", "release_date", "must be provided")
+	v.Check(model.MatchesReleaseDate(v, album.ReleaseDate), "release_date", "invalid format of release date")
+}
+
+func ValidateAlbumTrack(v *validator.Validator, track model.AlbumTrackInput) {
+	v.Check(track.SongID > 0, "song_id", "must be provided")
+	v.Check(track.Position <= 10_000, "position", "must be a maximum of 10 thousand")
+}
 
-	v.Check(len(song.Text) <= 1_048_576, "text", "must not be more than 1MB long")
+func ValidatePlaylist(v *validator.Validator, playlist *model.Playlist) {
+	v.Check(playlist.Name != "", "name", "must be provided")
+	v.Check(len(playlist.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.Check(len(playlist.Description) <= 5000, "description", "must not be more than 5000 bytes long")
+}
+
+func ValidatePlaylistEntry(v *validator.Validator, entry model.PlaylistEntryInput) {
+	v.Check(entry.SongID > 0, "song_id", "must be provided")
+	v.Check(entry.Position <= 10_000, "position", "must be a maximum of 10 thousand")
+}
 
-	v.Check(len(song.Link) <= 500, "link", "must not be more than 500 bytes long")
+func ValidatePlaylistMove(v *validator.Validator, move model.PlaylistMoveInput) {
+	v.Check(move.Position > 0, "position", "must be provided")
+	v.Check(move.Position <= 10_000, "position", "must be a maximum of 10 thousand")
 }
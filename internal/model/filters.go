@@ -1,16 +1,80 @@
 package model
 
+const (
+	MatchExact = "exact"
+	MatchFuzzy = "fuzzy"
+)
+
 type SongFilters struct {
-	Group       string
-	Song        string
-	ReleaseDate string
-	Text        string
-	Link        string
-	PageSize    uint
-	Page        uint
+	ArtistID     uint64
+	Group        string
+	Song         string
+	ReleaseDate  string
+	ReleasedFrom string
+	ReleasedTo   string
+	Text         string
+	Link         string
+	Album        string
+	Match        string
+	Threshold    *float64
+	Sort         string
+	PageSize     uint
+	Page         uint
+	// Keyset switches from page numbers to cursors, After and Before
+	// hold the cursor the page starts after or ends before.
+	Keyset bool
+	After  string
+	Before string
+}
+
+type ArtistFilters struct {
+	Name     string
+	PageSize uint
+	Page     uint
+}
+
+type AlbumFilters struct {
+	Group    string
+	Title    string
+	PageSize uint
+	Page     uint
+}
+
+type PlaylistFilters struct {
+	Name     string
+	PageSize uint
+	Page     uint
 }
 
+// SearchLanguages are the text search configurations songs can be indexed with.
+var SearchLanguages = []string{"simple", "english", "russian"}
+
+type SongSearchFilters struct {
+	Query    string
+	Language string
+	PageSize uint
+	Page     uint
+}
+
+type SongRevisionFilters struct {
+	SongID   uint64
+	PageSize uint
+	Page     uint
+}
+
+type TrashFilters struct {
+	PageSize uint
+	Page     uint
+}
+
+// Formats of the song text.
+const (
+	TextFormatPlain = "plain"
+	TextFormatTyped = "typed"
+)
+
 type SongTextFilters struct {
-	ID    uint64
-	Verse uint
+	ID     uint64
+	Verse  uint
+	Format string
 }
@@ -6,9 +6,90 @@ type SongInput struct {
 	ReleaseDate *string   `json:"releaseDate"`
 	Text        *[]string `json:"text"`
 	Link        *string   `json:"link"`
+	Language    *string   `json:"language"`
 }
 
+// HasDetails reports whether the input carries details of the song besides
+// its group and title.
+func (in SongInput) HasDetails() bool {
+	return in.ReleaseDate != nil || in.Text != nil || in.Link != nil || in.Language != nil
+}
+
+// SongInfo returns the song described by the input, the omitted fields being empty.
+func (in SongInput) SongInfo() *SongInfo {
+	var song SongInfo
+	if in.Group != nil {
+		song.Group = *in.Group
+	}
+	if in.Song != nil {
+		song.Song = *in.Song
+	}
+	if in.ReleaseDate != nil {
+		song.ReleaseDate = *in.ReleaseDate
+	}
+	if in.Text != nil {
+		song.Text = *in.Text
+	}
+	if in.Link != nil {
+		song.Link = *in.Link
+	}
+	if in.Language != nil {
+		song.Language = *in.Language
+	}
+	return &song
+}
+
+// Conflict resolutions of adding a song the group already has.
+const (
+	OnConflictFail   = "fail"
+	OnConflictUpdate = "update"
+)
+
+// SongsInput is a batch of songs to add, the group and the song
+// with the same index make a pair.
 type SongsInput struct {
 	Groups []string `json:"groups"`
 	Songs  []string `json:"songs"`
 }
+
+type AlbumInput struct {
+	Group       *string `json:"group"`
+	Title       *string `json:"title"`
+	ReleaseDate *string `json:"releaseDate"`
+}
+
+// VerseInput is a verse added to or replaced in the lyrics. Position is only
+// used by a new verse, which is appended if it is omitted.
+type VerseInput struct {
+	Position uint   `json:"position"`
+	Type     string `json:"type"`
+	Label    string `json:"label"`
+	Text     string `json:"text"`
+}
+
+// VerseOrderInput lists the current positions of all the verses in their new order.
+type VerseOrderInput struct {
+	Order []uint `json:"order"`
+}
+
+type AlbumTrackInput struct {
+	SongID   uint64 `json:"songId"`
+	Position uint   `json:"position"`
+}
+
+type PlaylistInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// PlaylistEntryInput is a song added to the playlist, it is appended
+// if Position is omitted.
+type PlaylistEntryInput struct {
+	SongID   uint64 `json:"songId"`
+	Position uint   `json:"position"`
+}
+
+// PlaylistMoveInput is the new position of the entry.
+type PlaylistMoveInput struct {
+	Position uint `json:"position"`
+}
@@ -1,10 +1,220 @@
 package model
 
+import "time"
+
 type SongInfo struct {
 	ID          uint64   `json:"id"`
+	ArtistID    uint64   `json:"artistId"`
 	Group       string   `json:"group"`
 	Song        string   `json:"song"`
 	ReleaseDate string   `json:"releaseDate"`
 	Text        []string `json:"text"`
 	Link        string   `json:"link"`
+	Language    string   `json:"language"`
+	Version     uint     `json:"version"`
+	// Sources tells where the details came from: the name of the provider
+	// that supplied each of the enriched fields, or "manual" if it has been
+	// edited since.
+	Sources map[string]string `json:"sources,omitempty"`
+	// EnrichedAt is when the providers were last asked about the song,
+	// nil if they never were.
+	EnrichedAt *time.Time `json:"enrichedAt,omitempty"`
+
+	// Verses, if set, are stored instead of the verses derived from Text,
+	// see SetVerses.
+	Verses []Verse `json:"-"`
+}
+
+// SetVerses replaces the lyrics with the verses, keeping their types and
+// labels. The verses are renumbered in their order.
+func (s *SongInfo) SetVerses(verses []Verse) {
+	s.Verses = make([]Verse, 0, len(verses))
+	s.Text = make([]string, 0, len(verses))
+	for i, verse := range verses {
+		verse.Position = uint(i + 1)
+		s.Verses = append(s.Verses, verse)
+		s.Text = append(s.Text, verse.Text)
+	}
+}
+
+// Fields of the song details supplied by the providers, the keys of SongInfo.Sources.
+const (
+	FieldReleaseDate = "releaseDate"
+	FieldLink        = "link"
+	FieldText        = "text"
+)
+
+// SourceManual is the source of a field edited through the API.
+const SourceManual = "manual"
+
+type Artist struct {
+	ID         uint64 `json:"id"`
+	Name       string `json:"name"`
+	TotalSongs uint   `json:"totalSongs"`
+}
+
+type Album struct {
+	ID          uint64       `json:"id"`
+	ArtistID    uint64       `json:"artistId"`
+	Group       string       `json:"group"`
+	Title       string       `json:"title"`
+	ReleaseDate string       `json:"releaseDate"`
+	Tracks      []AlbumTrack `json:"tracks"`
+}
+
+type AlbumTrack struct {
+	Position uint   `json:"position"`
+	SongID   uint64 `json:"songId"`
+	Song     string `json:"song"`
+}
+
+type Playlist struct {
+	ID          uint64          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Entries     []PlaylistEntry `json:"entries"`
+}
+
+// PlaylistEntry is a song in the playlist, the same song may be
+// in a playlist more than once.
+type PlaylistEntry struct {
+	ID       uint64  `json:"id"`
+	Position uint    `json:"position"`
+	Song     SongOut `json:"song"`
+}
+
+// Types of the parts of the lyrics.
+const (
+	VerseTypeVerse  = "verse"
+	VerseTypeChorus = "chorus"
+	VerseTypeBridge = "bridge"
+	VerseTypeIntro  = "intro"
+	VerseTypeOutro  = "outro"
+)
+
+var VerseTypes = []string{VerseTypeVerse, VerseTypeChorus, VerseTypeBridge, VerseTypeIntro, VerseTypeOutro}
+
+// Verse is a part of the lyrics, numbered from 1. The label tells apart parts
+// of the same type, e.g. "Verse 2" or "Final chorus".
+type Verse struct {
+	Position uint   `json:"position"`
+	Type     string `json:"type"`
+	Label    string `json:"label,omitempty"`
+	Text     string `json:"text"`
+}
+
+// VersesFromText numbers the verses of the plain text. A verse keeps the type
+// and label of a previous verse with the same text, so that editing the text
+// does not lose them even if the verses are moved; new verses are plain verses.
+func VersesFromText(text []string, previous []Verse) []Verse {
+	used := make([]bool, len(previous))
+	verses := make([]Verse, 0, len(text))
+
+	for i, t := range text {
+		verse := Verse{Position: uint(i + 1), Type: VerseTypeVerse, Text: t}
+		for j, p := range previous {
+			if !used[j] && p.Text == t {
+				verse.Type, verse.Label = p.Type, p.Label
+				used[j] = true
+				break
+			}
+		}
+		verses = append(verses, verse)
+	}
+	return verses
+}
+
+const (
+	RevisionActionCreate = "create"
+	RevisionActionUpdate = "update"
+	RevisionActionRevert = "revert"
+	// RevisionActionSnapshot is the first revision of the songs stored
+	// before the history was recorded.
+	RevisionActionSnapshot = "snapshot"
+)
+
+// SongChange describes who made a change to a song,
+// it is recorded in the revision created by the change.
+type SongChange struct {
+	Editor       string
+	RevertedFrom uint
+}
+
+type SongRevision struct {
+	SongID       uint64    `json:"songId"`
+	Revision     uint      `json:"revision"`
+	Group        string    `json:"group"`
+	Song         string    `json:"song"`
+	ReleaseDate  string    `json:"releaseDate"`
+	Text         []string  `json:"text"`
+	Link         string    `json:"link"`
+	Language     string    `json:"language"`
+	Action       string    `json:"action"`
+	RevertedFrom uint      `json:"revertedFrom,omitempty"`
+	Editor       string    `json:"editor"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// States of an asynchronous job.
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// Job adds a song in the background, fetching its details from the
+// external API. Once it is done Result holds one of the batch statuses
+// and SongID the song it is about, if any.
+type Job struct {
+	ID         uint64    `json:"id"`
+	Status     string    `json:"status"`
+	Group      string    `json:"group"`
+	Song       string    `json:"song"`
+	Editor     string    `json:"-"`
+	OnConflict string    `json:"-"`
+	Result     string    `json:"result,omitempty"`
+	SongID     uint64    `json:"songId,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	// Claims counts the times the job has been claimed, a worker only
+	// renews and finishes the job while its claim is the latest one.
+	Claims uint `json:"-"`
+}
+
+// Outcomes of re-enriching a song.
+const (
+	EnrichmentUpdated   = "updated"
+	EnrichmentUnchanged = "unchanged"
+	// EnrichmentSkipped is the outcome for a song whose details have all
+	// been edited by hand.
+	EnrichmentSkipped  = "skipped"
+	EnrichmentNotFound = "not_found"
+	EnrichmentFailed   = "failed"
+)
+
+// EnrichmentRun reports a run of the scheduled re-enrichment: what has
+// been done with every stale song it has looked up again.
+type EnrichmentRun struct {
+	StartedAt  time.Time          `json:"startedAt"`
+	FinishedAt time.Time          `json:"finishedAt"`
+	Updated    int                `json:"updated"`
+	Unchanged  int                `json:"unchanged"`
+	Skipped    int                `json:"skipped"`
+	NotFound   int                `json:"notFound"`
+	Failed     int                `json:"failed"`
+	Error      string             `json:"error,omitempty"`
+	Songs      []EnrichmentResult `json:"songs"`
+}
+
+// EnrichmentResult is the outcome of re-enriching the song, Fields lists
+// the updated ones.
+type EnrichmentResult struct {
+	SongID uint64   `json:"songId"`
+	Group  string   `json:"group"`
+	Song   string   `json:"song"`
+	Status string   `json:"status"`
+	Fields []string `json:"fields,omitempty"`
+	Error  string   `json:"error,omitempty"`
 }
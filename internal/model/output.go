@@ -1,22 +1,184 @@
 package model
 
+import (
+	"time"
+
+	"effective-mobile-song-library/pkg/breaker"
+)
+
 type ErrRes struct {
 	Error any `json:"error"`
 }
 
 type SongOut struct {
-	ID          uint64 `json:"id"`
-	Group       string `json:"group"`
-	Song        string `json:"song"`
-	ReleaseDate string `json:"releaseDate"`
-	Link        string `json:"link"`
-	TotalVerses uint   `json:"totalVerses"`
+	ID          uint64   `json:"id"`
+	Group       string   `json:"group"`
+	Song        string   `json:"song"`
+	ReleaseDate string   `json:"releaseDate"`
+	Link        string   `json:"link"`
+	TotalVerses uint     `json:"totalVerses"`
+	Score       *float64 `json:"score,omitempty"`
 }
 
 type Songs struct {
-	Songs []SongOut `json:"songs"`
+	Songs    []*SongOut `json:"songs"`
+	Metadata *Metadata  `json:"metadata,omitempty"`
+	Cursors  *Cursors   `json:"cursors,omitempty"`
+}
+
+// Metadata describes the page of a listing paginated by page numbers.
+// Only the total is set when nothing matched.
+type Metadata struct {
+	CurrentPage  uint `json:"currentPage,omitempty"`
+	PageSize     uint `json:"pageSize,omitempty"`
+	FirstPage    uint `json:"firstPage,omitempty"`
+	LastPage     uint `json:"lastPage,omitempty"`
+	TotalRecords uint `json:"totalRecords"`
+}
+
+func CalculateMetadata(totalRecords uint, page uint, pageSize uint) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     (totalRecords + pageSize - 1) / pageSize,
+		TotalRecords: totalRecords,
+	}
+}
+
+// Cursors are the opaque tokens of the neighbouring pages, to be passed
+// as the after (next) and before (prev) query parameters.
+type Cursors struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+type DeletedSongOut struct {
+	ID          uint64    `json:"id"`
+	Group       string    `json:"group"`
+	Song        string    `json:"song"`
+	ReleaseDate string    `json:"releaseDate"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+type SongSearchResult struct {
+	ID      uint64  `json:"id"`
+	Group   string  `json:"group"`
+	Song    string  `json:"song"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+	Verses  []uint  `json:"verses"`
 }
 
 type SongText struct {
 	Text string `json:"text"`
 }
+
+type SongVerses struct {
+	Verses []Verse `json:"verses"`
+}
+
+// Outcomes of adding a song of a batch.
+const (
+	BatchStatusCreated   = "created"
+	BatchStatusUpdated   = "updated"
+	BatchStatusDuplicate = "duplicate"
+	BatchStatusNotFound  = "not_found"
+	BatchStatusFailed    = "failed"
+)
+
+// SongBatchResult is the outcome of adding a song of a batch. ID is the song
+// that was added or updated, or the one the group already has.
+type SongBatchResult struct {
+	Group  string `json:"group"`
+	Song   string `json:"song"`
+	Status string `json:"status"`
+	ID     uint64 `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type SongBatch struct {
+	Results []SongBatchResult `json:"results"`
+}
+
+type AlbumOut struct {
+	ID          uint64 `json:"id"`
+	ArtistID    uint64 `json:"artistId"`
+	Group       string `json:"group"`
+	Title       string `json:"title"`
+	ReleaseDate string `json:"releaseDate"`
+	TotalTracks uint   `json:"totalTracks"`
+}
+
+type PlaylistOut struct {
+	ID           uint64 `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	TotalEntries uint   `json:"totalEntries"`
+}
+
+type SongRevisionOut struct {
+	Revision     uint      `json:"revision"`
+	Action       string    `json:"action"`
+	RevertedFrom uint      `json:"revertedFrom,omitempty"`
+	Editor       string    `json:"editor"`
+	CreatedAt    time.Time `json:"createdAt"`
+	TotalVerses  uint      `json:"totalVerses"`
+}
+
+type SongDiff struct {
+	From   uint          `json:"from"`
+	To     uint          `json:"to"`
+	Fields []FieldChange `json:"fields"`
+	Verses []VerseChange `json:"verses"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// VerseChange is a single step of the verse-level diff. FromVerse and ToVerse
+// are the verse numbers in the compared revisions, zero if the verse is absent there.
+type VerseChange struct {
+	Op        string `json:"op"`
+	FromVerse uint   `json:"fromVerse,omitempty"`
+	ToVerse   uint   `json:"toVerse,omitempty"`
+	Text      string `json:"text"`
+}
+
+// JobOut is the job along with the song it has added or updated.
+type JobOut struct {
+	*Job
+	SongInfo *SongInfo `json:"songInfo,omitempty"`
+}
+
+// Health reports whether the API is available and the state of the
+// circuit breakers guarding the providers of song details.
+type Health struct {
+	Status    string           `json:"status"`
+	Providers []ProviderHealth `json:"providers"`
+}
+
+type ProviderHealth struct {
+	Name string `json:"name"`
+	breaker.State
+	Cache *CacheStats `json:"cache,omitempty"`
+}
+
+// CacheStats counts the lookups of the provider's answers in the cache.
+// Negative hits are the songs the provider is known not to know.
+type CacheStats struct {
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negativeHits"`
+	Misses       uint64 `json:"misses"`
+}
+
+type EnrichmentRuns struct {
+	Runs []*EnrichmentRun `json:"runs"`
+}
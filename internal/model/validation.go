@@ -0,0 +1,70 @@
+package model
+
+import (
+	"fmt"
+
+	"effective-mobile-song-library/pkg/releasedate"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// MatchesReleaseDate reports whether the value is an existing date
+// in one of the "DD.MM.YYYY", "MM.YYYY" or "YYYY" formats.
+func MatchesReleaseDate(v *validator.Validator, value string) bool {
+	if !v.Matches(value, validator.ReleaseDateRX) &&
+		!v.Matches(value, validator.ReleaseYearMonthRX) &&
+		!v.Matches(value, validator.ReleaseYearRX) {
+		return false
+	}
+	_, _, err := releasedate.Parse(value)
+	return err == nil
+}
+
+// Size limits of the lyrics: the verses of a song take at most maxTextBytes
+// together and MaxVerseBytes each.
+const (
+	maxTextBytes  = 1 << 20
+	MaxVerseBytes = 64 << 10
+	maxVerses     = 10_000
+)
+
+// ValidateSongInfo checks the details of a song before they are saved,
+// whether they come from the API or from the providers.
+func ValidateSongInfo(v *validator.Validator, song *SongInfo) {
+	validateSongDetails(v, song)
+	v.Check(validator.PermittedValue(song.Language, SearchLanguages...), "language", "must be one of: simple, english, russian")
+}
+
+// ValidateNewSongInfo checks the song added with its details, the language
+// may be omitted to be detected from the lyrics.
+func ValidateNewSongInfo(v *validator.Validator, song *SongInfo) {
+	validateSongDetails(v, song)
+	if song.Language != "" {
+		v.Check(validator.PermittedValue(song.Language, SearchLanguages...), "language", "must be one of: simple, english, russian")
+	}
+}
+
+func validateSongDetails(v *validator.Validator, song *SongInfo) {
+	v.Check(song.Group != "", "group", "must be provided")
+	v.Check(song.Song != "", "song", "must be provided")
+
+	v.Check(song.ReleaseDate != "", "release_date", "must be provided")
+	v.Check(MatchesReleaseDate(v, song.ReleaseDate), "release_date", "invalid format of release date")
+
+	validateText(v, song.Text)
+
+	v.Check(len(song.Link) <= 500, "link", "must not be more than 500 bytes long")
+}
+
+// validateText checks the size of the lyrics as a whole and of every verse.
+func validateText(v *validator.Validator, text []string) {
+	v.Check(len(text) <= maxVerses, "text", "must not contain more than 10 thousand verses")
+
+	var size int
+	for i, verse := range text {
+		size += len(verse)
+		if len(verse) > MaxVerseBytes {
+			v.AddError("text", fmt.Sprintf("verse %d must not be more than 64KB long", i+1))
+		}
+	}
+	v.Check(size <= maxTextBytes, "text", "must not be more than 1MB long")
+}
@@ -0,0 +1,179 @@
+// Package cache keeps the answers of the song details providers, so that a
+// song looked up recently is not asked for again. Songs a provider does not
+// know are remembered too, for a shorter time.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/pkg/breaker"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/lru"
+)
+
+type (
+	// Source is the provider whose answers are cached.
+	Source interface {
+		Name() string
+		GetSongInfoWithDetails(ctx context.Context, group string, song string) (*model.SongInfo, error)
+		BreakerState() breaker.State
+	}
+
+	// Store keeps the answers until they expire, a nil song standing
+	// for a song the provider does not know.
+	Store interface {
+		Get(ctx context.Context, key string) (song *model.SongInfo, ok bool, err error)
+		Set(ctx context.Context, key string, song *model.SongInfo, ttl time.Duration) error
+	}
+)
+
+// Provider caches the answers of the source. Failures other than an unknown
+// song are not cached, and neither are they while the store is unavailable:
+// the source is asked then.
+type Provider struct {
+	source      Source
+	store       Store
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+func NewProvider(source Source, store Store, ttl time.Duration, negativeTTL time.Duration) *Provider {
+	return &Provider{
+		source:      source,
+		store:       store,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.source.Name()
+}
+
+func (p *Provider) BreakerState() breaker.State {
+	return p.source.BreakerState()
+}
+
+type refreshKey struct{}
+
+// Refresh returns a context whose lookups skip the cached answers and ask
+// the source again, the answers replacing the cached ones. It is meant for
+// songs being fetched again because their details may have changed.
+func Refresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+func refreshing(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+// CacheStats returns the counters of the cache lookups.
+func (p *Provider) CacheStats() model.CacheStats {
+	return model.CacheStats{
+		Hits:         p.hits.Load(),
+		NegativeHits: p.negativeHits.Load(),
+		Misses:       p.misses.Load(),
+	}
+}
+
+// GetSongInfoWithDetails returns the cached details of the song, or fetches
+// them from the source. external.ErrBadRequest is returned for a song the
+// source is known not to know. See Refresh for bypassing the cache.
+func (p *Provider) GetSongInfoWithDetails(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	key := p.key(group, song)
+
+	var cached *model.SongInfo
+	var ok bool
+	var err error
+	if !refreshing(ctx) {
+		cached, ok, err = p.store.Get(ctx, key)
+	}
+	switch {
+	case err != nil:
+		logger.PrintError(fmt.Errorf("song info cache: %w", err), map[string]any{
+			"provider": p.source.Name(),
+		})
+	case ok && cached == nil:
+		p.negativeHits.Add(1)
+		return nil, fmt.Errorf("%w: unknown song (cached)", external.ErrBadRequest)
+	case ok:
+		p.hits.Add(1)
+		return clone(cached), nil
+	}
+	p.misses.Add(1)
+
+	songInfo, err := p.source.GetSongInfoWithDetails(ctx, group, song)
+	switch {
+	case err == nil:
+		p.set(ctx, key, clone(songInfo), p.ttl)
+	case errors.Is(err, external.ErrBadRequest):
+		p.set(ctx, key, nil, p.negativeTTL)
+	}
+	return songInfo, err
+}
+
+func (p *Provider) set(ctx context.Context, key string, song *model.SongInfo, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	err := p.store.Set(ctx, key, song, ttl)
+	if err != nil {
+		logger.PrintError(fmt.Errorf("song info cache: %w", err), map[string]any{
+			"provider": p.source.Name(),
+		})
+	}
+}
+
+// key identifies the song of the provider, ignoring the case
+// and the spacing of the names.
+func (p *Provider) key(group string, song string) string {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.Join(strings.Fields(name), " "))
+	}
+	return p.source.Name() + "\x00" + normalize(group) + "\x00" + normalize(song)
+}
+
+func clone(song *model.SongInfo) *model.SongInfo {
+	if song == nil {
+		return nil
+	}
+	c := *song
+	c.Text = slices.Clone(song.Text)
+	c.Sources = maps.Clone(song.Sources)
+	c.Verses = slices.Clone(song.Verses)
+	return &c
+}
+
+// MemoryStore keeps up to a given number of answers in memory.
+type MemoryStore struct {
+	cache *lru.Cache[string, *model.SongInfo]
+}
+
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{cache: lru.New[string, *model.SongInfo](size)}
+}
+
+func (ms *MemoryStore) Get(ctx context.Context, key string) (*model.SongInfo, bool, error) {
+	song, ok := ms.cache.Get(key)
+	return song, ok, nil
+}
+
+func (ms *MemoryStore) Set(ctx context.Context, key string, song *model.SongInfo, ttl time.Duration) error {
+	ms.cache.Set(key, song, ttl)
+	return nil
+}
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"effective-mobile-song-library/internal/model"
+)
+
+func (sr *SongsRepository) GetAlbum(ctx context.Context, id uint64) (*model.Album, error) {
+	query := `
+	SELECT al.album_id, al.artist_id, a.name, al.title, al.release_date, al.release_precision
+	FROM albums al
+	JOIN artists a ON a.artist_id = al.artist_id
+	WHERE al.album_id=$1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var album model.Album
+	var rd releaseDate
+
+	err := sr.db.QueryRowContext(ctx, query, id).Scan(
+		&album.ID,
+		&album.ArtistID,
+		&album.Group,
+		&album.Title,
+		&rd.date,
+		&rd.precision,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	album.ReleaseDate = rd.String()
+
+	tracksQuery := `
+	SELECT t.position, t.song_id, s.song
+	FROM album_tracks t
+	JOIN songs s ON s.song_id = t.song_id AND s.deleted_at IS NULL
+	WHERE t.album_id=$1
+	ORDER BY t.position ASC`
+
+	rows, err := sr.db.QueryContext(ctx, tracksQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	album.Tracks = []model.AlbumTrack{}
+
+	for rows.Next() {
+		var track model.AlbumTrack
+		err := rows.Scan(
+			&track.Position,
+			&track.SongID,
+			&track.Song,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		album.Tracks = append(album.Tracks, track)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &album, nil
+}
+
+func (sr *SongsRepository) GetAlbums(ctx context.Context, filters model.AlbumFilters) ([]*model.AlbumOut, error) {
+	query := `
+	SELECT al.album_id, al.artist_id, a.name, al.title, al.release_date, al.release_precision, COUNT(t.song_id)
+	FROM albums al
+	JOIN artists a ON a.artist_id = al.artist_id
+	LEFT JOIN (
+		album_tracks t
+		JOIN songs s ON s.song_id = t.song_id AND s.deleted_at IS NULL
+	) ON t.album_id = al.album_id
+	WHERE ($1 = '' OR LOWER(a.name)=LOWER($1))
+	AND ($2 = '' OR al.title ILIKE '%' || $2 || '%')
+	GROUP BY al.album_id, a.name
+	ORDER BY al.album_id ASC
+	LIMIT $3 OFFSET $4`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{
+		normalizeName(filters.Group),
+		escapeLike(filters.Title),
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	albums := []*model.AlbumOut{}
+
+	for rows.Next() {
+		var album model.AlbumOut
+		var rd releaseDate
+		err := rows.Scan(
+			&album.ID,
+			&album.ArtistID,
+			&album.Group,
+			&album.Title,
+			&rd.date,
+			&rd.precision,
+			&album.TotalTracks,
+		)
+		if err != nil {
+			return nil, err
+		}
+		album.ReleaseDate = rd.String()
+
+		albums = append(albums, &album)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return albums, nil
+}
+
+func (sr *SongsRepository) InsertAlbum(ctx context.Context, album *model.Album) error {
+	query := `
+	WITH artist AS (` + upsertArtistQuery + `)
+	INSERT INTO albums (artist_id, title, release_date, release_precision)
+	SELECT artist_id, $2, $3, $4 FROM artist
+	RETURNING album_id, artist_id, (SELECT name FROM artist)`
+
+	rd := newReleaseDate(album.ReleaseDate)
+
+	args := []any{
+		normalizeName(album.Group),
+		album.Title,
+		rd.date,
+		rd.precision,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	return sr.db.QueryRowContext(ctx, query, args...).Scan(&album.ID, &album.ArtistID, &album.Group)
+}
+
+func (sr *SongsRepository) UpdateAlbum(ctx context.Context, album *model.Album) error {
+	query := `
+	WITH target AS (
+		SELECT album_id
+		FROM albums
+		WHERE album_id = $5
+		FOR UPDATE
+	), artist AS (` + upsertTargetArtistQuery + `)
+	UPDATE albums
+	SET artist_id = artist.artist_id, title = $2, release_date = $3, release_precision = $4
+	FROM artist, target
+	WHERE albums.album_id = target.album_id
+	RETURNING albums.artist_id, artist.name`
+
+	rd := newReleaseDate(album.ReleaseDate)
+
+	args := []any{
+		normalizeName(album.Group),
+		album.Title,
+		rd.date,
+		rd.precision,
+		album.ID,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	err := sr.db.QueryRowContext(ctx, query, args...).Scan(&album.ArtistID, &album.Group)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sr *SongsRepository) DeleteAlbum(ctx context.Context, id uint64) error {
+	query := `
+	DELETE FROM albums
+	WHERE album_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	result, err := sr.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// SetAlbumTrack puts the song on the album at the given position, shifting the
+// following tracks down. If the song is already on the album it is moved.
+// A zero or too large position appends the song to the end of the album.
+func (sr *SongsRepository) SetAlbumTrack(ctx context.Context, albumID uint64, track model.AlbumTrackInput) error {
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockAlbum(ctx, tx, albumID)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM songs WHERE song_id = $1 AND deleted_at IS NULL)`, track.SongID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRecordNotFound
+	}
+
+	err = removeAlbumTrack(ctx, tx, albumID, track.SongID)
+	if err != nil && !errors.Is(err, ErrRecordNotFound) {
+		return err
+	}
+
+	var total uint
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM album_tracks WHERE album_id = $1`, albumID).Scan(&total)
+	if err != nil {
+		return err
+	}
+
+	position := track.Position
+	if position == 0 || position > total+1 {
+		position = total + 1
+	}
+
+	shiftQuery := `
+	UPDATE album_tracks
+	SET position = position + 1
+	WHERE album_id = $1 AND position >= $2`
+
+	_, err = tx.ExecContext(ctx, shiftQuery, albumID, position)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := `
+	INSERT INTO album_tracks (album_id, song_id, position)
+	VALUES ($1, $2, $3)`
+
+	_, err = tx.ExecContext(ctx, insertQuery, albumID, track.SongID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (sr *SongsRepository) RemoveAlbumTrack(ctx context.Context, albumID uint64, songID uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockAlbum(ctx, tx, albumID)
+	if err != nil {
+		return err
+	}
+
+	err = removeAlbumTrack(ctx, tx, albumID, songID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockAlbum locks the album row so that concurrent track changes
+// of the same album are serialized.
+func lockAlbum(ctx context.Context, tx *sql.Tx, albumID uint64) error {
+	var id uint64
+	err := tx.QueryRowContext(ctx, `SELECT album_id FROM albums WHERE album_id = $1 FOR UPDATE`, albumID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// removeAlbumTrack deletes the song from the album and closes the gap
+// left in the track positions.
+func removeAlbumTrack(ctx context.Context, tx *sql.Tx, albumID uint64, songID uint64) error {
+	deleteQuery := `
+	DELETE FROM album_tracks
+	WHERE album_id = $1 AND song_id = $2
+	RETURNING position`
+
+	var position uint
+	err := tx.QueryRowContext(ctx, deleteQuery, albumID, songID).Scan(&position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	shiftQuery := `
+	UPDATE album_tracks
+	SET position = position - 1
+	WHERE album_id = $1 AND position > $2`
+
+	_, err = tx.ExecContext(ctx, shiftQuery, albumID, position)
+	return err
+}
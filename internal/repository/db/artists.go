@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"effective-mobile-song-library/internal/model"
+)
+
+// upsertArtistQuery resolves an artist by its case-insensitive name ($1),
+// creating it if it does not exist yet. It always returns a row, so it can be
+// used as a CTE in song inserts and updates.
+const upsertArtistQuery = `
+	INSERT INTO artists (name)
+	VALUES ($1)
+	ON CONFLICT ((LOWER(name))) DO UPDATE SET name = artists.name
+	RETURNING artist_id, name`
+
+// upsertTargetArtistQuery is upsertArtistQuery for updates: the artist is
+// only resolved once the "target" CTE has matched the row being updated, so
+// a missing or changed row does not leave an orphan artist behind.
+const upsertTargetArtistQuery = `
+	INSERT INTO artists (name)
+	SELECT $1 FROM target
+	ON CONFLICT ((LOWER(name))) DO UPDATE SET name = artists.name
+	RETURNING artist_id, name`
+
+// normalizeName trims the name and collapses inner whitespace,
+// so that "Muse" and " Muse " resolve to the same artist.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// likeEscaper escapes the LIKE wildcards, so that a "%" or "_" typed in a
+// filter matches itself. Backslash is the default LIKE escape in Postgres.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike prepares a substring filter for '%' || $n || '%' patterns.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func (sr *SongsRepository) GetArtist(ctx context.Context, id uint64) (*model.Artist, error) {
+	query := `
+	SELECT a.artist_id, a.name, COUNT(s.song_id)
+	FROM artists a
+	LEFT JOIN songs s ON s.artist_id = a.artist_id AND s.deleted_at IS NULL
+	WHERE a.artist_id=$1
+	GROUP BY a.artist_id`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var artist model.Artist
+
+	err := sr.db.QueryRowContext(ctx, query, id).Scan(
+		&artist.ID,
+		&artist.Name,
+		&artist.TotalSongs,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &artist, nil
+}
+
+func (sr *SongsRepository) GetArtists(ctx context.Context, filters model.ArtistFilters) ([]*model.Artist, error) {
+	query := `
+	SELECT a.artist_id, a.name, COUNT(s.song_id)
+	FROM artists a
+	LEFT JOIN songs s ON s.artist_id = a.artist_id AND s.deleted_at IS NULL
+	WHERE ($1 = '' OR a.name ILIKE '%' || $1 || '%')
+	GROUP BY a.artist_id
+	ORDER BY a.artist_id ASC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{
+		escapeLike(normalizeName(filters.Name)),
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artists := []*model.Artist{}
+
+	for rows.Next() {
+		var artist model.Artist
+		err := rows.Scan(
+			&artist.ID,
+			&artist.Name,
+			&artist.TotalSongs,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		artists = append(artists, &artist)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return artists, nil
+}
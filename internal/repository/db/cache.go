@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+)
+
+// SongInfoCache keeps the answers of the song details providers in the
+// database, so that they survive restarts. A NULL song info stands for a song
+// the provider does not know.
+type SongInfoCache struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+func NewSongInfoCache(db *sql.DB, timeout time.Duration) *SongInfoCache {
+	return &SongInfoCache{db: db, timeout: timeout}
+}
+
+// Get returns the cached answer, ok is false if there is none or it has expired.
+func (sc *SongInfoCache) Get(ctx context.Context, key string) (*model.SongInfo, bool, error) {
+	query := `
+	SELECT song_info
+	FROM song_info_cache
+	WHERE key = $1 AND expires_at > now()`
+
+	ctx, cancel := context.WithTimeout(ctx, sc.timeout)
+	defer cancel()
+
+	var songInfo []byte
+	err := sc.db.QueryRowContext(ctx, query, key).Scan(&songInfo)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, false, nil
+		default:
+			return nil, false, err
+		}
+	}
+
+	if songInfo == nil {
+		return nil, true, nil
+	}
+
+	var song model.SongInfo
+	err = json.Unmarshal(songInfo, &song)
+	if err != nil {
+		return nil, false, err
+	}
+	return &song, true, nil
+}
+
+// Set saves the answer for the ttl. The expired answers are deleted
+// along the way, so that the table does not grow forever.
+func (sc *SongInfoCache) Set(ctx context.Context, key string, song *model.SongInfo, ttl time.Duration) error {
+	query := `
+	WITH expired AS (
+		DELETE FROM song_info_cache WHERE expires_at <= now() AND key <> $1
+	)
+	INSERT INTO song_info_cache (key, song_info, expires_at)
+	VALUES ($1, $2, now() + make_interval(secs => $3))
+	ON CONFLICT (key) DO UPDATE
+	SET song_info = EXCLUDED.song_info, expires_at = EXCLUDED.expires_at`
+
+	var songInfo any
+	if song != nil {
+		b, err := json.Marshal(song)
+		if err != nil {
+			return err
+		}
+		songInfo = string(b)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sc.timeout)
+	defer cancel()
+
+	_, err := sc.db.ExecContext(ctx, query, key, songInfo, ttl.Seconds())
+	return err
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// GetStaleSongs returns up to limit songs due to be enriched again: never
+// enriched, enriched before staleBefore, or missing some details and enriched
+// before incompleteBefore. The songs that have failed to be enriched after
+// failedBefore are left out. The songs enriched the longest ago come first.
+func (sr *SongsRepository) GetStaleSongs(ctx context.Context, staleBefore time.Time, incompleteBefore time.Time, failedBefore time.Time, limit int) ([]uint64, error) {
+	query := `
+	SELECT s.song_id
+	FROM songs s
+	WHERE s.deleted_at IS NULL AND (s.enrich_failed_at IS NULL OR s.enrich_failed_at < $3) AND (
+		s.enriched_at IS NULL OR s.enriched_at < $1 OR (
+			s.enriched_at < $2 AND (
+				s.release_date IS NULL OR COALESCE(s.link, '') = '' OR
+				NOT EXISTS (SELECT 1 FROM song_verses v WHERE v.song_id = s.song_id)
+			)
+		)
+	)
+	ORDER BY s.enriched_at ASC NULLS FIRST, s.song_id ASC
+	LIMIT $4`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	rows, err := sr.db.QueryContext(ctx, query, staleBefore, incompleteBefore, failedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []uint64{}
+	for rows.Next() {
+		var id uint64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// SetEnrichedAt records when the song was enriched without changing it,
+// no revision is recorded.
+func (sr *SongsRepository) SetEnrichedAt(ctx context.Context, id uint64, at time.Time) error {
+	query := `
+	UPDATE songs
+	SET enriched_at = $2
+	WHERE song_id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	_, err := sr.db.ExecContext(ctx, query, id, at)
+	return err
+}
+
+// SetEnrichmentFailedAt records when the song has failed to be enriched,
+// no revision is recorded.
+func (sr *SongsRepository) SetEnrichmentFailedAt(ctx context.Context, id uint64, at time.Time) error {
+	query := `
+	UPDATE songs
+	SET enrich_failed_at = $2
+	WHERE song_id = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	_, err := sr.db.ExecContext(ctx, query, id, at)
+	return err
+}
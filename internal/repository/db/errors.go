@@ -1,8 +1,44 @@
 package db
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
 
 var (
 	ErrRecordNotFound = errors.New("record not found")
 	ErrEditConflict   = errors.New("edit conflict")
-)
\ No newline at end of file
+	ErrInvalidCursor  = errors.New("invalid cursor")
+)
+
+// DuplicateSongError is returned when the group already has a song with the
+// same title, compared case-insensitively and with whitespace collapsed.
+// Songs in the trash do not count.
+type DuplicateSongError struct {
+	// ID of the existing song
+	ID uint64
+}
+
+func (e *DuplicateSongError) Error() string {
+	return fmt.Sprintf("song already exists with id %d", e.ID)
+}
+
+// isUniqueViolation reports whether the statement failed on the unique constraint.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// IsTimeout reports whether the operation was aborted because it took too long.
+// A statement canceled by its context is reported by the driver as a
+// query_canceled error rather than the context error.
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "57014" {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+)
+
+const jobColumns = `job_id, status, "group", song, editor, on_conflict, result, COALESCE(song_id, 0), error, created_at, updated_at, claims`
+
+// InsertJob saves a new pending job.
+func (sr *SongsRepository) InsertJob(ctx context.Context, job *model.Job) error {
+	query := `
+	INSERT INTO jobs ("group", song, editor, on_conflict)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + jobColumns
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	return scanJob(sr.db.QueryRowContext(ctx, query, job.Group, job.Song, job.Editor, job.OnConflict), job)
+}
+
+func (sr *SongsRepository) GetJob(ctx context.Context, id uint64) (*model.Job, error) {
+	query := `
+	SELECT ` + jobColumns + `
+	FROM jobs
+	WHERE job_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var job model.Job
+	err := scanJob(sr.db.QueryRowContext(ctx, query, id), &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimJob marks the oldest pending job as running and returns it,
+// ErrRecordNotFound is returned if no job is pending. Concurrent workers
+// never claim the same job.
+func (sr *SongsRepository) ClaimJob(ctx context.Context) (*model.Job, error) {
+	query := `
+	UPDATE jobs
+	SET status = 'running', updated_at = now(), claims = claims + 1
+	WHERE job_id = (
+		SELECT job_id
+		FROM jobs
+		WHERE status = 'pending'
+		ORDER BY job_id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + jobColumns
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var job model.Job
+	err := scanJob(sr.db.QueryRowContext(ctx, query), &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// FinishJob saves the status and the outcome of the job. ErrEditConflict is
+// returned if the job has been requeued or claimed again since it was claimed.
+func (sr *SongsRepository) FinishJob(ctx context.Context, job *model.Job) error {
+	query := `
+	UPDATE jobs
+	SET status = $2, result = $3, song_id = NULLIF($4, 0), error = $5, updated_at = now()
+	WHERE job_id = $1 AND status = 'running' AND claims = $6
+	RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{job.ID, job.Status, job.Result, int64(job.SongID), job.Error, job.Claims}
+	err := sr.db.QueryRowContext(ctx, query, args...).Scan(&job.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
+	return err
+}
+
+// RenewJob extends the lease of the running job, so that it is not requeued.
+// ErrEditConflict is returned if the job has been requeued or claimed again
+// since it was claimed.
+func (sr *SongsRepository) RenewJob(ctx context.Context, job *model.Job) error {
+	query := `
+	UPDATE jobs
+	SET updated_at = now()
+	WHERE job_id = $1 AND status = 'running' AND claims = $2
+	RETURNING updated_at`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	err := sr.db.QueryRowContext(ctx, query, job.ID, job.Claims).Scan(&job.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrEditConflict
+	}
+	return err
+}
+
+// RequeueJobs makes the jobs left running, e.g. by a crash, pending again
+// and returns their number. Only the jobs whose lease has not been renewed
+// since runningBefore are requeued, the others are still being run.
+func (sr *SongsRepository) RequeueJobs(ctx context.Context, runningBefore time.Time) (int64, error) {
+	query := `
+	UPDATE jobs
+	SET status = 'pending', updated_at = now()
+	WHERE status = 'running' AND updated_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	result, err := sr.db.ExecContext(ctx, query, runningBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanJob(row *sql.Row, job *model.Job) error {
+	err := row.Scan(
+		&job.ID,
+		&job.Status,
+		&job.Group,
+		&job.Song,
+		&job.Editor,
+		&job.OnConflict,
+		&job.Result,
+		&job.SongID,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.Claims,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"effective-mobile-song-library/internal/repository/keyset"
+)
+
+// sortKeys renders the select expression returning the values of the sort
+// columns of a row as text, they are used to build the page cursors.
+func sortKeys(columns []keyset.Column) string {
+	keys := make([]string, 0, len(columns))
+	for _, col := range columns {
+		keys = append(keys, col.Expr+"::text")
+	}
+	return "ARRAY[" + strings.Join(keys, ", ") + "]::text[]"
+}
+
+// keysetCondition builds the keyset condition for the cursor keys,
+// numbering the placeholders starting from next.
+func keysetCondition(columns []keyset.Column, keys []*string, backward bool, next int) (string, []any) {
+	var args []any
+	condition := keyset.Condition(columns, keys, backward, func(value string, cast string) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d::%s", next+len(args)-1, cast)
+	})
+	return condition, args
+}
+
+// nullStrings converts the scanned sort keys for the cursors.
+func nullStrings(values []sql.NullString) []*string {
+	out := make([]*string, len(values))
+	for i, value := range values {
+		if value.Valid {
+			out[i] = &value.String
+		}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql"
+
+	"effective-mobile-song-library/pkg/releasedate"
+)
+
+// releaseDate is the database representation of a textual release date:
+// a real date and the precision it is known with.
+type releaseDate struct {
+	date      sql.NullTime
+	precision sql.NullString
+}
+
+// newReleaseDate converts the textual release date. Empty or unparseable
+// values are stored as NULL.
+func newReleaseDate(value string) releaseDate {
+	t, p, err := releasedate.Parse(value)
+	if err != nil {
+		return releaseDate{}
+	}
+	return releaseDate{
+		date:      sql.NullTime{Time: t, Valid: true},
+		precision: sql.NullString{String: string(p), Valid: true},
+	}
+}
+
+func (rd releaseDate) String() string {
+	if !rd.date.Valid {
+		return ""
+	}
+	return releasedate.Format(rd.date.Time, releasedate.Precision(rd.precision.String))
+}
+
+// periodArgs returns the bounds of the period covered by the textual date,
+// or NULLs if the value is empty.
+func periodArgs(value string) (from any, to any) {
+	if value == "" {
+		return nil, nil
+	}
+	f, t, err := releasedate.ParsePeriod(value)
+	if err != nil {
+		return nil, nil
+	}
+	return f, t
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"effective-mobile-song-library/internal/model"
+
+	"github.com/lib/pq"
+)
+
+func (sr *SongsRepository) GetRevisions(ctx context.Context, filters model.SongRevisionFilters) ([]*model.SongRevisionOut, error) {
+	query := `
+	SELECT revision, action, COALESCE(reverted_from, 0), editor, created_at, COALESCE(cardinality(song_text), 0)
+	FROM song_revisions
+	WHERE song_id = $1
+	ORDER BY revision DESC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{
+		filters.SongID,
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*model.SongRevisionOut{}
+
+	for rows.Next() {
+		var revision model.SongRevisionOut
+		err := rows.Scan(
+			&revision.Revision,
+			&revision.Action,
+			&revision.RevertedFrom,
+			&revision.Editor,
+			&revision.CreatedAt,
+			&revision.TotalVerses,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (sr *SongsRepository) GetRevision(ctx context.Context, songID uint64, revision uint) (*model.SongRevision, error) {
+	query := `
+	SELECT song_id, revision, "group", song, release_date, release_precision, song_text, COALESCE(link, ''), language,
+		action, COALESCE(reverted_from, 0), editor, created_at
+	FROM song_revisions
+	WHERE song_id = $1 AND revision = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var rev model.SongRevision
+	var rd releaseDate
+
+	err := sr.db.QueryRowContext(ctx, query, songID, revision).Scan(
+		&rev.SongID,
+		&rev.Revision,
+		&rev.Group,
+		&rev.Song,
+		&rd.date,
+		&rd.precision,
+		pq.Array(&rev.Text),
+		&rev.Link,
+		&rev.Language,
+		&rev.Action,
+		&rev.RevertedFrom,
+		&rev.Editor,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	rev.ReleaseDate = rd.String()
+
+	return &rev, nil
+}
+
+// insertRevision stores the snapshot of the song as the revision
+// with the number of its current version.
+func insertRevision(ctx context.Context, tx *sql.Tx, song *model.SongInfo, action string, change model.SongChange) error {
+	query := `
+	INSERT INTO song_revisions (song_id, revision, "group", song, release_date, release_precision, song_text, link, language,
+		action, reverted_from, editor)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, 0), $12)`
+
+	rd := newReleaseDate(song.ReleaseDate)
+
+	args := []any{
+		song.ID,
+		song.Version,
+		song.Group,
+		song.Song,
+		rd.date,
+		rd.precision,
+		pq.Array(song.Text),
+		song.Link,
+		song.Language,
+		action,
+		change.RevertedFrom,
+		change.Editor,
+	}
+
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	"effective-mobile-song-library/internal/model"
+
+	"github.com/lib/pq"
+)
+
+// ts_headline marks the matches with these private use characters, removed
+// from the lyrics beforehand, so that the snippet can be escaped as HTML
+// before they are replaced with <mark> tags.
+const (
+	headlineStart = "\uE000"
+	headlineStop  = "\uE001"
+)
+
+var headlineTags = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// Search runs a ranked full-text search over song titles and lyrics.
+// The query is parsed with the text search configuration of each song,
+// so word forms are matched according to the song's language.
+func (sr *SongsRepository) Search(ctx context.Context, filters model.SongSearchFilters) ([]*model.SongSearchResult, error) {
+	query := `
+	WITH q AS (
+		SELECT l.language, websearch_to_tsquery(l.language::regconfig, $1) AS query
+		FROM unnest($2::text[]) AS l(language)
+	),
+	matches AS (
+		SELECT s.song_id, s.artist_id, s.song, s.language, q.query,
+			ts_rank_cd(s.search_vector, q.query) AS rank
+		FROM songs s
+		JOIN q ON q.language = s.language
+		WHERE s.search_vector @@ q.query AND s.deleted_at IS NULL
+		ORDER BY rank DESC, s.song_id ASC
+		LIMIT $3 OFFSET $4
+	)
+	SELECT m.song_id, a.name, m.song, m.rank,
+		COALESCE(ts_headline(
+			m.language::regconfig,
+			translate((SELECT string_agg(v.text, E'\n\n' ORDER BY v.position) FROM song_verses v WHERE v.song_id = m.song_id), $5, ''),
+			m.query,
+			$6
+		), ''),
+		ARRAY(
+			SELECT v.position
+			FROM song_verses v
+			WHERE v.song_id = m.song_id AND to_tsvector(m.language::regconfig, v.text) @@ m.query
+			ORDER BY v.position
+		)
+	FROM matches m
+	JOIN artists a ON a.artist_id = m.artist_id
+	ORDER BY m.rank DESC, m.song_id ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	languages := model.SearchLanguages
+	if filters.Language != "" {
+		languages = []string{filters.Language}
+	}
+
+	args := []any{
+		filters.Query,
+		pq.Array(languages),
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+		headlineStart + headlineStop,
+		`StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", MaxFragments=3, FragmentDelimiter=" ... "`,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*model.SongSearchResult{}
+
+	for rows.Next() {
+		var result model.SongSearchResult
+		var verses []int64
+		err := rows.Scan(
+			&result.ID,
+			&result.Group,
+			&result.Song,
+			&result.Rank,
+			&result.Snippet,
+			pq.Array(&verses),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Snippet = headlineTags.Replace(html.EscapeString(result.Snippet))
+
+		result.Verses = make([]uint, 0, len(verses))
+		for _, verse := range verses {
+			result.Verses = append(result.Verses, uint(verse))
+		}
+
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
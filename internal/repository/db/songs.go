@@ -4,40 +4,75 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/keyset"
 
 	"github.com/lib/pq"
 )
 
 type SongsRepository struct {
 	db *sql.DB
+	// timeout bounds every storage operation
+	timeout time.Duration
 }
 
-func NewSongsRepository(db *sql.DB) *SongsRepository {
-	return &SongsRepository{db: db}
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
-func (sr *SongsRepository) Get(id uint64) (*model.SongInfo, error) {
+func NewSongsRepository(db *sql.DB, timeout time.Duration) *SongsRepository {
+	return &SongsRepository{db: db, timeout: timeout}
+}
+
+func (sr *SongsRepository) Get(ctx context.Context, id uint64) (*model.SongInfo, error) {
+	return sr.getSong(ctx, `s.song_id=$1`, id)
+}
+
+// titleKey is the expression song titles are unique by within a group.
+const titleKey = `LOWER(TRIM(regexp_replace(%s, '\s+', ' ', 'g')))`
+
+// GetByTitle returns the song of the group with the given title, compared
+// the way the uniqueness of songs is enforced.
+func (sr *SongsRepository) GetByTitle(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	where := fmt.Sprintf(`LOWER(a.name)=LOWER($1) AND %s=%s`, fmt.Sprintf(titleKey, "s.song"), fmt.Sprintf(titleKey, "$2"))
+	return sr.getSong(ctx, where, normalizeName(group), song)
+}
+
+// getSong returns the song matching the condition that is not in the trash.
+func (sr *SongsRepository) getSong(ctx context.Context, where string, args ...any) (*model.SongInfo, error) {
 	query := `
-	SELECT song_id, "group", song, release_date, song_text, link
-	FROM songs
-	WHERE song_id=$1`
+	SELECT s.song_id, s.artist_id, a.name, s.song, s.release_date, s.release_precision, ` + songTextQuery + `, s.link, s.language, s.version, s.sources, s.enriched_at
+	FROM songs s
+	JOIN artists a ON a.artist_id = s.artist_id
+	WHERE ` + where + ` AND s.deleted_at IS NULL`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
 	var songInfo model.SongInfo
+	var rd releaseDate
+	var enrichedAt sql.NullTime
 
-	err := sr.db.QueryRowContext(ctx, query, id).Scan(
+	err := sr.db.QueryRowContext(ctx, query, args...).Scan(
 		&songInfo.ID,
+		&songInfo.ArtistID,
 		&songInfo.Group,
 		&songInfo.Song,
-		&songInfo.ReleaseDate,
+		&rd.date,
+		&rd.precision,
 		pq.Array(&songInfo.Text),
 		&songInfo.Link,
+		&songInfo.Language,
+		&songInfo.Version,
+		(*sources)(&songInfo.Sources),
+		&enrichedAt,
 	)
 	if err != nil {
 		switch {
@@ -47,80 +82,296 @@ func (sr *SongsRepository) Get(id uint64) (*model.SongInfo, error) {
 			return nil, err
 		}
 	}
+	songInfo.ReleaseDate = rd.String()
+	if enrichedAt.Valid {
+		songInfo.EnrichedAt = &enrichedAt.Time
+	}
+
 	return &songInfo, nil
 }
 
-func (sr *SongsRepository) GetAll(filters model.SongFilters) ([]*model.SongInfo, error) {
-	query := `
-	SELECT song_id, "group", song, release_date, song_text, link
-	FROM songs
-	WHERE ($1 = '' OR LOWER("group")=LOWER($1))
-	AND ($2 = '' OR LOWER(song)=LOWER($2))
-	AND ($3 = '' OR release_date LIKE '%' || $3 || '%')
-	AND (
-		$4 = '' OR
-		EXISTS (
-			SELECT 1
-			FROM unnest(song_text) as verse
-			WHERE verse LIKE '%' || $4 || '%'
+// duplicateSong turns the violation of the unique title constraint into the
+// error reporting the existing song of the group with the same title.
+func (sr *SongsRepository) duplicateSong(ctx context.Context, err error, group string, song string) error {
+	if !isUniqueViolation(err, "songs_artist_song_idx") {
+		return err
+	}
+	existing, lookupErr := sr.GetByTitle(ctx, group, song)
+	if lookupErr != nil {
+		return err
+	}
+	return &DuplicateSongError{ID: existing.ID}
+}
+
+// songSortColumns maps the sort keys accepted by the API to the columns they order by.
+var songSortColumns = map[string]keyset.Column{
+	"id":          {Expr: "s.song_id", Cast: "bigint", Kind: keyset.KindInteger},
+	"group":       {Expr: "LOWER(a.name)", Cast: "text"},
+	"song":        {Expr: "LOWER(s.song)", Cast: "text"},
+	"releaseDate": {Expr: "s.release_date", Cast: "date", Kind: keyset.KindDate, Nullable: true},
+}
+
+// GetAll returns a page of songs along with the pagination metadata, the total
+// being counted by the same query. In keyset mode the page starts after (or ends
+// before) the row the cursor points at and the cursors of the neighbouring
+// pages are returned instead.
+func (sr *SongsRepository) GetAll(ctx context.Context, filters model.SongFilters) (*model.Songs, error) {
+	order := songOrder(filters.Sort)
+	backward := filters.Before != ""
+	limit := filters.PageSize
+	offset := (filters.Page - 1) * filters.PageSize
+	keysetClause := "TRUE"
+	var keysetArgs []any
+
+	token := filters.After
+	if backward {
+		token = filters.Before
+	}
+	if filters.Keyset {
+		if token != "" {
+			keys, err := keyset.Decode(token, filters.Sort, order)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			keysetClause, keysetArgs = keysetCondition(order, keys, backward, 13)
+		}
+		// one more row tells whether there is a further page
+		limit++
+		offset = 0
+	}
+
+	orderClause := keyset.OrderBy(order, backward)
+	if filters.Sort == "" && filters.Match == model.MatchFuzzy {
+		orderClause = "score DESC NULLS LAST, s.song_id ASC"
+	}
+
+	groupCondition := "LOWER(a.name)=LOWER($1)"
+	songCondition := "LOWER(s.song)=LOWER($2)"
+	score := "NULL::real"
+	if filters.Match == model.MatchFuzzy {
+		// The % operator uses pg_trgm.similarity_threshold, set below for the transaction,
+		// which lets the trigram indexes be used.
+		groupCondition = "LOWER(a.name) % LOWER($1)"
+		songCondition = "LOWER(s.song) % LOWER($2)"
+		score = `CASE
+			WHEN $1 <> '' AND $2 <> '' THEN (similarity(LOWER(a.name), LOWER($1)) + similarity(LOWER(s.song), LOWER($2))) / 2
+			WHEN $1 <> '' THEN similarity(LOWER(a.name), LOWER($1))
+			WHEN $2 <> '' THEN similarity(LOWER(s.song), LOWER($2))
+		END`
+	}
+
+	// The total is counted apart from the page, so that it is known past
+	// the last page too: an empty page is a single row without a song.
+	total := "SELECT COUNT(*) AS total FROM matched"
+	if filters.Keyset {
+		total = "SELECT 0 AS total"
+	}
+
+	query := fmt.Sprintf(`
+	WITH matched AS (
+		SELECT s.song_id, %s AS score
+		FROM songs s
+		JOIN artists a ON a.artist_id = s.artist_id
+		WHERE s.deleted_at IS NULL
+		AND ($1 = '' OR %s)
+		AND ($2 = '' OR %s)
+		AND (
+			$3::date IS NULL OR
+			(
+				s.release_date >= $3::date AND
+				s.release_date + CASE s.release_precision
+					WHEN 'year' THEN interval '1 year'
+					WHEN 'month' THEN interval '1 month'
+					ELSE interval '1 day'
+				END <= $4::date
+			)
 		)
+		AND (
+			$5 = '' OR
+			EXISTS (
+				SELECT 1
+				FROM song_verses v
+				WHERE v.song_id = s.song_id AND v.text LIKE '%%' || $5 || '%%'
+			)
+		)
+		AND (s.link=$6 OR $6 = '')
+		AND ($7::bigint = 0 OR s.artist_id = $7)
+		AND (
+			$8 = '' OR
+			EXISTS (
+				SELECT 1
+				FROM album_tracks t
+				JOIN albums al ON al.album_id = t.album_id
+				WHERE t.song_id = s.song_id AND LOWER(al.title) = LOWER($8)
+			)
+		)
+		AND ($9::date IS NULL OR s.release_date >= $9::date)
+		AND ($10::date IS NULL OR s.release_date < $10::date)
+	),
+	page AS (
+		SELECT s.song_id, a.name, s.song, s.release_date, s.release_precision, s.link,
+			(SELECT COUNT(*) FROM song_verses v WHERE v.song_id = s.song_id) AS total_verses, m.score, %s AS sort_keys,
+			ROW_NUMBER() OVER (ORDER BY %s) AS ord
+		FROM matched m
+		JOIN songs s ON s.song_id = m.song_id
+		JOIN artists a ON a.artist_id = s.artist_id
+		WHERE %s
+		ORDER BY %s
+		LIMIT $11 OFFSET $12
 	)
-	AND (link=$5 OR $5 = '')
-	ORDER BY song_id ASC
-	LIMIT $6 OFFSET $7`
+	SELECT COALESCE(p.song_id, 0), COALESCE(p.name, ''), COALESCE(p.song, ''), p.release_date, p.release_precision, COALESCE(p.link, ''),
+		COALESCE(p.total_verses, 0), p.score, p.sort_keys, t.total
+	FROM (%s) t
+	LEFT JOIN page p ON TRUE
+	ORDER BY p.ord`, score, groupCondition, songCondition, sortKeys(order), orderClause, keysetClause, orderClause, total)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
+	releaseFrom, releaseTo := periodArgs(filters.ReleaseDate)
+	releasedFrom, _ := periodArgs(filters.ReleasedFrom)
+	_, releasedTo := periodArgs(filters.ReleasedTo)
+
 	args := []any{
-		filters.Group,
+		normalizeName(filters.Group),
 		filters.Song,
-		filters.ReleaseDate,
-		filters.Text,
+		releaseFrom,
+		releaseTo,
+		escapeLike(filters.Text),
 		filters.Link,
-		filters.PageSize,
-		(filters.Page - 1) * filters.PageSize,
+		filters.ArtistID,
+		filters.Album,
+		releasedFrom,
+		releasedTo,
+		limit,
+		offset,
 	}
+	args = append(args, keysetArgs...)
 
-	rows, err := sr.db.QueryContext(ctx, query, args...)
+	var q queryer = sr.db
+	if filters.Match == model.MatchFuzzy {
+		tx, err := sr.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+
+		threshold := strconv.FormatFloat(*filters.Threshold, 'f', -1, 64)
+		_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true)`, threshold)
+		if err != nil {
+			return nil, err
+		}
+		q = tx
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	songs := []*model.SongInfo{}
+	songs := []*model.SongOut{}
+	var keys [][]*string
+	var totalRecords uint
 
 	for rows.Next() {
-		var songInfo model.SongInfo
+		var song model.SongOut
+		var rd releaseDate
+		var score sql.NullFloat64
+		var rowKeys []sql.NullString
 		err := rows.Scan(
-			&songInfo.ID,
-			&songInfo.Group,
-			&songInfo.Song,
-			&songInfo.ReleaseDate,
-			pq.Array(&songInfo.Text),
-			&songInfo.Link,
+			&song.ID,
+			&song.Group,
+			&song.Song,
+			&rd.date,
+			&rd.precision,
+			&song.Link,
+			&song.TotalVerses,
+			&score,
+			pq.Array(&rowKeys),
+			&totalRecords,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if song.ID == 0 {
+			continue
+		}
+		song.ReleaseDate = rd.String()
+		if score.Valid {
+			song.Score = &score.Float64
+		}
 
-		songs = append(songs, &songInfo)
+		songs = append(songs, &song)
+		keys = append(keys, nullStrings(rowKeys))
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return songs, nil
+	if !filters.Keyset {
+		metadata := model.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		return &model.Songs{Songs: songs, Metadata: &metadata}, nil
+	}
+
+	more := uint(len(songs)) > filters.PageSize
+	if more {
+		songs = songs[:filters.PageSize]
+		keys = keys[:filters.PageSize]
+	}
+	if backward {
+		slices.Reverse(songs)
+		slices.Reverse(keys)
+	}
+
+	var first, last []*string
+	if len(keys) > 0 {
+		first, last = keys[0], keys[len(keys)-1]
+	}
+	cursors := keyset.Cursors(filters.Sort, token, backward, more, first, last)
+
+	return &model.Songs{Songs: songs, Cursors: cursors}, nil
 }
 
-func (sr *SongsRepository) GetFullText(id uint64) (*string, error) {
+// songOrder returns the columns ordering the songs for a comma-separated list
+// of sort keys such as "-releaseDate,group", a "-" prefix meaning descending.
+// Keys must be validated beforehand, unknown ones are skipped. The song ID
+// breaks the remaining ties.
+func songOrder(sort string) []keyset.Column {
+	var order []keyset.Column
+	byID := false
+
+	for _, key := range strings.Split(sort, ",") {
+		column, ok := songSortColumns[strings.TrimPrefix(key, "-")]
+		if !ok {
+			continue
+		}
+		column.Desc = strings.HasPrefix(key, "-")
+		order = append(order, column)
+
+		if column.Expr == songSortColumns["id"].Expr {
+			// the ID is unique, later keys would never be compared
+			byID = true
+			break
+		}
+	}
+
+	if !byID {
+		order = append(order, songSortColumns["id"])
+	}
+	return order
+}
+
+func (sr *SongsRepository) GetFullText(ctx context.Context, id uint64) (*string, error) {
 	query := `
-	SELECT song_text
-	FROM songs
-	WHERE (song_id = $1)`
+	SELECT v.text
+	FROM song_verses v
+	JOIN songs s ON s.song_id = v.song_id
+	WHERE (v.song_id = $1 AND s.deleted_at IS NULL)
+	ORDER BY v.position ASC`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
 	args := []any{
@@ -136,10 +387,12 @@ func (sr *SongsRepository) GetFullText(id uint64) (*string, error) {
 	var fullText []string
 
 	for rows.Next() {
-		err := rows.Scan(pq.Array(&fullText))
+		var verse string
+		err := rows.Scan(&verse)
 		if err != nil {
 			return nil, err
 		}
+		fullText = append(fullText, verse)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -151,13 +404,14 @@ func (sr *SongsRepository) GetFullText(id uint64) (*string, error) {
 	return &out, nil
 }
 
-func (sr *SongsRepository) GetText(filters model.SongTextFilters) (*string, error) {
+func (sr *SongsRepository) GetText(ctx context.Context, filters model.SongTextFilters) (*string, error) {
 	query := `
-	SELECT song_text[$2]
-	FROM songs
-	WHERE (song_id = $1)`
+	SELECT v.text
+	FROM song_verses v
+	JOIN songs s ON s.song_id = v.song_id
+	WHERE (v.song_id = $1 AND v.position = $2 AND s.deleted_at IS NULL)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
 	args := []any{
@@ -187,61 +441,142 @@ func (sr *SongsRepository) GetText(filters model.SongTextFilters) (*string, erro
 	return &text, nil
 }
 
-func (sr *SongsRepository) Insert(song *model.SongInfo) error {
+// Insert inserts the song and records its first revision.
+func (sr *SongsRepository) Insert(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
 	query := `
-	INSERT INTO songs ("group", song, release_date, song_text, link)
-	VALUES ($1, $2, $3, $4, $5)
-	RETURNING song_id`
+	WITH artist AS (` + upsertArtistQuery + `)
+	INSERT INTO songs (artist_id, song, release_date, release_precision, link, language, sources, enriched_at)
+	SELECT artist_id, $2, $3, $4, $5, $6, $7, $8 FROM artist
+	RETURNING song_id, artist_id, (SELECT name FROM artist), version`
+
+	rd := newReleaseDate(song.ReleaseDate)
 
 	args := []any{
-		song.Group,
+		normalizeName(song.Group),
 		song.Song,
-		song.ReleaseDate,
-		pq.Array(song.Text),
+		rd.date,
+		rd.precision,
 		song.Link,
+		song.Language,
+		sources(song.Sources),
+		song.EnrichedAt,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
-	return sr.db.QueryRowContext(ctx, query, args...).Scan(&song.ID)
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&song.ID, &song.ArtistID, &song.Group, &song.Version)
+	if err != nil {
+		return sr.duplicateSong(ctx, err, song.Group, song.Song)
+	}
+
+	err = setVerses(ctx, tx, song)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(ctx, tx, song, model.RevisionActionCreate, change)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (sr *SongsRepository) Update(song *model.SongInfo) error {
+// Update updates the song if its version has not changed since it was fetched
+// and records the new revision. ErrEditConflict is returned otherwise.
+func (sr *SongsRepository) Update(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
 	query := `
+	WITH target AS (
+		SELECT song_id
+		FROM songs
+		WHERE song_id = $7 AND version = $8 AND deleted_at IS NULL
+		FOR UPDATE
+	), artist AS (` + upsertTargetArtistQuery + `)
 	UPDATE songs
-	SET "group" = $2, song = $3, release_date = $4, song_text = $5, link = $6
-	WHERE song_id = $1`
+	SET artist_id = artist.artist_id, song = $2, release_date = $3, release_precision = $4, link = $5, language = $6,
+		sources = $9, enriched_at = $10, version = version + 1
+	FROM artist, target
+	WHERE songs.song_id = target.song_id
+	RETURNING songs.artist_id, artist.name, songs.version`
+
+	rd := newReleaseDate(song.ReleaseDate)
 
 	args := []any{
-		song.ID,
-		song.Group,
+		normalizeName(song.Group),
 		song.Song,
-		song.ReleaseDate,
-		pq.Array(song.Text),
+		rd.date,
+		rd.precision,
 		song.Link,
+		song.Language,
+		song.ID,
+		song.Version,
+		sources(song.Sources),
+		song.EnrichedAt,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
-	_, err := sr.db.ExecContext(ctx, query, args...)
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&song.ArtistID, &song.Group, &song.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return sr.duplicateSong(ctx, err, song.Group, song.Song)
+		}
+	}
+
+	err = setVerses(ctx, tx, song)
+	if err != nil {
+		return err
+	}
+
+	action := model.RevisionActionUpdate
+	if change.RevertedFrom != 0 {
+		action = model.RevisionActionRevert
+	}
+
+	err = insertRevision(ctx, tx, song, action, change)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func (sr *SongsRepository) Delete(id uint64) error {
+// Delete moves the song to the trash. A non-zero version makes the deletion
+// conditional: ErrEditConflict is returned if the song has been changed since.
+// The song is removed from the playlists, restoring it does not put it back.
+func (sr *SongsRepository) Delete(ctx context.Context, id uint64, version uint) error {
 	query := `
-	DELETE FROM songs
-	WHERE song_id = $1`
+	UPDATE songs
+	SET deleted_at = now()
+	WHERE song_id = $1 AND deleted_at IS NULL AND ($2::integer = 0 OR version = $2)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
 	defer cancel()
 
-	result, err := sr.db.ExecContext(ctx, query, id)
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, version)
 	if err != nil {
 		return err
 	}
@@ -252,7 +587,25 @@ func (sr *SongsRepository) Delete(id uint64) error {
 	}
 
 	if rowsAffected == 0 {
+		if version == 0 {
+			return ErrRecordNotFound
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM songs WHERE song_id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEditConflict
+		}
 		return ErrRecordNotFound
 	}
-	return nil
+
+	err = removeSongEntries(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// sources is the jsonb object naming the provider of every enriched field.
+type sources map[string]string
+
+func (s sources) Value() (driver.Value, error) {
+	if s == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]string(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *sources) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, (*map[string]string)(s))
+	case string:
+		return json.Unmarshal([]byte(src), (*map[string]string)(s))
+	}
+	return fmt.Errorf("cannot scan %T into sources", src)
+}
@@ -0,0 +1,42 @@
+package external
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrBadRequest = errors.New("incorrect request")
+	ErrNoResponse = errors.New("no response from API")
+
+	// ErrCircuitOpen is returned without calling the external API
+	// while it is considered down.
+	ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrNoResponse)
+
+	// ErrUnknownSong is returned when none of the providers knows the song.
+	ErrUnknownSong = fmt.Errorf("%w: the song is not known to the providers", ErrBadRequest)
+)
+
+// StatusError is returned when the external API responds with a status other
+// than 200 OK. It matches ErrBadRequest for 400 Bad Request and ErrNoResponse
+// otherwise.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("external API responded with %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNoResponse:
+		return e.StatusCode != http.StatusBadRequest
+	}
+	return false
+}
@@ -1,40 +1,144 @@
 package external
 
 import (
+	"context"
 	"effective-mobile-song-library/config"
 	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/pkg/breaker"
+	"effective-mobile-song-library/pkg/logger"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 )
 
-var (
-	ErrBadRequest = errors.New("incorrect request")
-	ErrNoResponse = errors.New("no response from API")
-)
+// maxRetryDelay caps the exponential backoff between the attempts.
+const maxRetryDelay = 5 * time.Second
 
+// ApiClient fetches the song details from one of the providers.
 type ApiClient struct {
-	config *config.Config
+	config  config.Provider
+	client  *http.Client
+	breaker *breaker.Breaker
+}
+
+// NewHTTPClient returns the HTTP client for the provider, every attempt
+// is limited by its attempt timeout.
+func NewHTTPClient(config config.Provider) *http.Client {
+	return &http.Client{
+		Timeout: config.AttemptTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   5 * time.Second,
+			ResponseHeaderTimeout: config.AttemptTimeout,
+			MaxIdleConnsPerHost:   10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
 }
 
-func NewApiClient(config *config.Config) *ApiClient {
-	return &ApiClient{config: config}
+func NewApiClient(config config.Provider, client *http.Client) *ApiClient {
+	return &ApiClient{
+		config:  config,
+		client:  client,
+		breaker: breaker.New(config.BreakerThreshold, config.BreakerCooldown),
+	}
 }
 
-func (ac *ApiClient) GetSongInfoWithDetails(group string, song string) (*model.SongInfo, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/info?group=%s&song=%s", ac.config.ExternalAPIURL, url.PathEscape(group), url.PathEscape(song)))
+// Name returns the name of the provider.
+func (ac *ApiClient) Name() string {
+	return ac.config.Name
+}
+
+// BreakerState returns the state of the circuit breaker guarding the provider.
+func (ac *ApiClient) BreakerState() breaker.State {
+	return ac.breaker.State()
+}
+
+// GetSongInfoWithDetails fetches the details of the song. Failed attempts
+// (5xx, 429 and network errors) are retried with exponential backoff, as long
+// as the provider's timeout allows. ErrCircuitOpen is returned right away
+// while the provider is considered down.
+func (ac *ApiClient) GetSongInfoWithDetails(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, ac.config.Timeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		token, ok := ac.breaker.Allow()
+		if !ok {
+			return nil, ErrCircuitOpen
+		}
+
+		songInfo, err := ac.getSongInfo(ctx, group, song)
+		switch {
+		case err == nil, errors.Is(err, ErrBadRequest):
+			ac.breaker.Success(token)
+			return songInfo, err
+		case errors.Is(err, context.Canceled):
+			// the caller has gone, it tells nothing about the external API
+			ac.breaker.Release(token)
+			return nil, err
+		}
+
+		// an unexpected answer, such as 404 or an invalid body, is a failure
+		// of the external API too, though asking again would not help
+		ac.breaker.Failure(token)
+		if !retryable(err) || attempt >= ac.config.Retries {
+			return nil, err
+		}
+
+		delay := ac.retryDelay(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// there is no time left to wait for the next attempt
+			return nil, err
+		}
+
+		logger.PrintDebug("retrying provider request", map[string]any{
+			"provider": ac.config.Name,
+			"group":    group,
+			"song":     song,
+			"attempt":  attempt + 1,
+			"delay":    delay.String(),
+			"error":    err.Error(),
+		})
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+func (ac *ApiClient) getSongInfo(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	query := url.Values{"group": {group}, "song": {song}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ac.config.URL+"/info?"+query.Encode(), nil)
 	if err != nil {
 		return nil, err
 	}
+
+	resp, err := ac.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoResponse, err)
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		if resp.StatusCode == http.StatusBadRequest {
-			return nil, ErrBadRequest
-		} else {
-			return nil, ErrNoResponse
+		// read what is left so that the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 		}
 	}
 
@@ -45,3 +149,51 @@ func (ac *ApiClient) GetSongInfoWithDetails(group string, song string) (*model.S
 
 	return songInfoWithDetailsDTO.ToModel(), nil
 }
+
+// retryable reports whether another attempt may succeed: the external API
+// is failing or overloaded, or could not be reached.
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryDelay returns how long to wait before the attempt following the
+// given one: as long as the external API asked for in Retry-After, otherwise
+// twice as long as the last time with some jitter.
+func (ac *ApiClient) retryDelay(attempt int, err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter
+	}
+
+	delay := maxRetryDelay
+	if attempt < 16 {
+		delay = min(ac.config.RetryDelay<<attempt, maxRetryDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// parseRetryAfter parses the Retry-After header, given either in seconds
+// or as an HTTP date. Zero is returned if it is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+// Package keyset builds the SQL of keyset (cursor) pagination shared by the
+// SQL storages and the cursors of the neighbouring pages.
+package keyset
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/pkg/cursor"
+)
+
+// Kind is the type of the values of a sort column, the cursor keys
+// are checked against it.
+type Kind int
+
+const (
+	KindText Kind = iota
+	KindInteger
+	KindDate // YYYY-MM-DD
+)
+
+// Column is a column of the ORDER BY clause of a listing. NULL values
+// are always ordered last.
+type Column struct {
+	Expr     string
+	Cast     string // type the cursor value is compared as
+	Kind     Kind
+	Desc     bool
+	Nullable bool
+}
+
+// Placeholder adds the cursor value to the query arguments and returns
+// the SQL it is referenced with.
+type Placeholder func(value string, cast string) string
+
+// OrderBy renders the ORDER BY clause, reversed when paging backwards.
+func OrderBy(columns []Column, reverse bool) string {
+	terms := make([]string, 0, len(columns))
+	for _, col := range columns {
+		direction := "ASC"
+		if col.Desc != reverse {
+			direction = "DESC"
+		}
+		term := col.Expr + " " + direction
+		if col.Nullable {
+			if reverse {
+				term += " NULLS FIRST"
+			} else {
+				term += " NULLS LAST"
+			}
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, ", ")
+}
+
+// Condition builds the condition selecting the rows that come after
+// (or before when backward is set) the row with the given sort keys.
+func Condition(columns []Column, keys []*string, backward bool, placeholder Placeholder) string {
+	var clauses []string
+	var equal []string
+
+	for i, col := range columns {
+		value := ""
+		if keys[i] != nil {
+			value = placeholder(*keys[i], col.Cast)
+		}
+
+		if cmp := comparison(col, value, backward); cmp != "" {
+			clauses = append(clauses, "("+strings.Join(append(slices.Clone(equal), cmp), " AND ")+")")
+		}
+
+		if value == "" {
+			equal = append(equal, col.Expr+" IS NULL")
+		} else {
+			equal = append(equal, fmt.Sprintf("%s = %s", col.Expr, value))
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "FALSE"
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")"
+}
+
+// comparison compares the column with the cursor value, an empty value
+// stands for NULL. Since NULLs are ordered last nothing follows a NULL key
+// and everything not NULL precedes it.
+func comparison(col Column, value string, backward bool) string {
+	if value == "" {
+		if backward {
+			return col.Expr + " IS NOT NULL"
+		}
+		return ""
+	}
+
+	op := ">"
+	if col.Desc != backward {
+		op = "<"
+	}
+	cmp := fmt.Sprintf("%s %s %s", col.Expr, op, value)
+	if col.Nullable && !backward {
+		cmp = fmt.Sprintf("(%s OR %s IS NULL)", cmp, col.Expr)
+	}
+	return cmp
+}
+
+// Decode decodes the token and checks that it was issued for the same
+// sort order, and that its keys are values of the sort columns.
+func Decode(token string, sort string, columns []Column) ([]*string, error) {
+	c, err := cursor.Decode(token)
+	if err != nil || c.Sort != sort || len(c.Keys) != len(columns) {
+		return nil, cursor.ErrInvalid
+	}
+
+	for i, col := range columns {
+		if !validKey(col, c.Keys[i]) {
+			return nil, cursor.ErrInvalid
+		}
+	}
+	return c.Keys, nil
+}
+
+func validKey(col Column, key *string) bool {
+	if key == nil {
+		return col.Nullable
+	}
+
+	switch col.Kind {
+	case KindInteger:
+		_, err := strconv.ParseInt(*key, 10, 64)
+		return err == nil
+	case KindDate:
+		_, err := time.Parse(time.DateOnly, *key)
+		return err == nil
+	}
+	return true
+}
+
+// Cursors returns the cursors of the pages around the one requested with
+// the token, given the sort keys of its first and last rows (nil for an empty
+// page) and whether more rows follow in the paging direction.
+func Cursors(sort string, token string, backward bool, more bool, first []*string, last []*string) *model.Cursors {
+	// The cursor the page was requested with leads back to where the client
+	// came from, the first and last rows lead further.
+	firstToken, lastToken := token, token
+	if first != nil {
+		firstToken = cursor.Encode(cursor.Cursor{Sort: sort, Keys: first})
+		lastToken = cursor.Encode(cursor.Cursor{Sort: sort, Keys: last})
+	}
+
+	cursors := &model.Cursors{}
+	if backward {
+		cursors.Next = lastToken
+		if more {
+			cursors.Prev = firstToken
+		}
+	} else {
+		if token != "" {
+			cursors.Prev = firstToken
+		}
+		if more {
+			cursors.Next = lastToken
+		}
+	}
+	return cursors
+}
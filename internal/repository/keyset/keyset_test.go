@@ -0,0 +1,99 @@
+package keyset
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"effective-mobile-song-library/pkg/cursor"
+)
+
+func key(s string) *string {
+	return &s
+}
+
+func TestDecode(t *testing.T) {
+	columns := []Column{
+		{Expr: "s.release_date", Cast: "date", Kind: KindDate, Nullable: true},
+		{Expr: "a.name", Kind: KindText},
+		{Expr: "s.song_id", Cast: "bigint", Kind: KindInteger},
+	}
+	const sort = "releaseDate,group,id"
+
+	tests := []struct {
+		name  string
+		token string
+		sort  string
+		want  []*string
+		err   error
+	}{
+		{
+			name:  "valid keys",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{key("2006-07-16"), key("Muse"), key("11")}}),
+			sort:  sort,
+			want:  []*string{key("2006-07-16"), key("Muse"), key("11")},
+		},
+		{
+			name:  "null key of a nullable column",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{nil, key("Muse"), key("11")}}),
+			sort:  sort,
+			want:  []*string{nil, key("Muse"), key("11")},
+		},
+		{
+			name:  "null key of a column that is never null",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{key("2006-07-16"), key("Muse"), nil}}),
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+		{
+			name:  "text key of an integer column",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{key("2006-07-16"), key("Muse"), key("eleven")}}),
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+		{
+			name:  "integer key of a date column",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{key("11"), key("Muse"), key("11")}}),
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+		{
+			name:  "date key in the format of the API",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{key("16.07.2006"), key("Muse"), key("11")}}),
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+		{
+			name:  "issued for another sort order",
+			token: cursor.Encode(cursor.Cursor{Sort: "id", Keys: []*string{key("2006-07-16"), key("Muse"), key("11")}}),
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+		{
+			name:  "fewer keys than columns",
+			token: cursor.Encode(cursor.Cursor{Sort: sort, Keys: []*string{key("2006-07-16"), key("Muse")}}),
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+		{
+			name:  "not a token",
+			token: "not a token",
+			sort:  sort,
+			err:   cursor.ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.token, tt.sort, columns)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if !slices.EqualFunc(got, tt.want, func(a, b *string) bool {
+				return a == nil && b == nil || a != nil && b != nil && *a == *b
+			}) {
+				t.Errorf("got keys %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetAlbum(ctx context.Context, id uint64) (*model.Album, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	al, ok := sr.albums[id]
+	if !ok {
+		return nil, db.ErrRecordNotFound
+	}
+
+	album := &model.Album{
+		ID:          al.id,
+		ArtistID:    al.artistID,
+		Group:       sr.artists[al.artistID].name,
+		Title:       al.title,
+		ReleaseDate: al.releaseDate,
+		Tracks:      []model.AlbumTrack{},
+	}
+	for _, t := range al.tracks {
+		if s, ok := sr.live(t.songID); ok {
+			album.Tracks = append(album.Tracks, model.AlbumTrack{Position: t.position, SongID: t.songID, Song: s.info.Song})
+		}
+	}
+	return album, nil
+}
+
+func (sr *SongsRepository) GetAlbums(ctx context.Context, filters model.AlbumFilters) ([]*model.AlbumOut, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	group := strings.ToLower(normalizeName(filters.Group))
+	title := strings.ToLower(filters.Title)
+
+	albums := []*model.AlbumOut{}
+	for _, id := range sortedIDs(sr.albums) {
+		al := sr.albums[id]
+		name := sr.artists[al.artistID].name
+		if group != "" && strings.ToLower(name) != group {
+			continue
+		}
+		if title != "" && !strings.Contains(strings.ToLower(al.title), title) {
+			continue
+		}
+
+		album := &model.AlbumOut{
+			ID:          al.id,
+			ArtistID:    al.artistID,
+			Group:       name,
+			Title:       al.title,
+			ReleaseDate: al.releaseDate,
+		}
+		for _, t := range al.tracks {
+			if _, ok := sr.live(t.songID); ok {
+				album.TotalTracks++
+			}
+		}
+		albums = append(albums, album)
+	}
+
+	return paginate(albums, filters.Page, filters.PageSize), nil
+}
+
+func (sr *SongsRepository) InsertAlbum(ctx context.Context, album *model.Album) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	a := sr.upsertArtist(album.Group)
+
+	sr.lastAlbumID++
+	album.ID = sr.lastAlbumID
+	album.ArtistID = a.id
+	album.Group = a.name
+
+	sr.albums[album.ID] = &albumRecord{
+		id:          album.ID,
+		artistID:    a.id,
+		title:       album.Title,
+		releaseDate: canonicalReleaseDate(album.ReleaseDate),
+	}
+	return nil
+}
+
+func (sr *SongsRepository) UpdateAlbum(ctx context.Context, album *model.Album) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	al, ok := sr.albums[album.ID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+
+	a := sr.upsertArtist(album.Group)
+	album.ArtistID = a.id
+	album.Group = a.name
+
+	al.artistID = a.id
+	al.title = album.Title
+	al.releaseDate = canonicalReleaseDate(album.ReleaseDate)
+	return nil
+}
+
+func (sr *SongsRepository) DeleteAlbum(ctx context.Context, id uint64) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if _, ok := sr.albums[id]; !ok {
+		return db.ErrRecordNotFound
+	}
+	delete(sr.albums, id)
+	return nil
+}
+
+// SetAlbumTrack puts the song on the album at the given position, shifting the
+// following tracks down. If the song is already on the album it is moved.
+// A zero or too large position appends the song to the end of the album.
+func (sr *SongsRepository) SetAlbumTrack(ctx context.Context, albumID uint64, t model.AlbumTrackInput) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	al, ok := sr.albums[albumID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+	if _, ok := sr.live(t.SongID); !ok {
+		return db.ErrRecordNotFound
+	}
+
+	al.removeTrack(t.SongID)
+
+	total := uint(len(al.tracks))
+	position := t.Position
+	if position == 0 || position > total+1 {
+		position = total + 1
+	}
+
+	for i := range al.tracks {
+		if al.tracks[i].position >= position {
+			al.tracks[i].position++
+		}
+	}
+	al.tracks = append(al.tracks, track{position: position, songID: t.SongID})
+	slices.SortFunc(al.tracks, func(a, b track) int {
+		return int(a.position) - int(b.position)
+	})
+	return nil
+}
+
+func (sr *SongsRepository) RemoveAlbumTrack(ctx context.Context, albumID uint64, songID uint64) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	al, ok := sr.albums[albumID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+	if !al.removeTrack(songID) {
+		return db.ErrRecordNotFound
+	}
+	return nil
+}
+
+// removeTrack deletes the song from the album and closes the gap
+// left in the track positions. It reports whether the song was on the album.
+func (al *albumRecord) removeTrack(songID uint64) bool {
+	i := slices.IndexFunc(al.tracks, func(t track) bool { return t.songID == songID })
+	if i < 0 {
+		return false
+	}
+
+	position := al.tracks[i].position
+	al.tracks = slices.Delete(al.tracks, i, i+1)
+	for i := range al.tracks {
+		if al.tracks[i].position > position {
+			al.tracks[i].position--
+		}
+	}
+	return true
+}
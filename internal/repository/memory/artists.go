@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetArtist(ctx context.Context, id uint64) (*model.Artist, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	a, ok := sr.artists[id]
+	if !ok {
+		return nil, db.ErrRecordNotFound
+	}
+	return sr.artist(a), nil
+}
+
+func (sr *SongsRepository) GetArtists(ctx context.Context, filters model.ArtistFilters) ([]*model.Artist, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	name := strings.ToLower(normalizeName(filters.Name))
+
+	artists := []*model.Artist{}
+	for _, id := range sortedIDs(sr.artists) {
+		a := sr.artists[id]
+		if name != "" && !strings.Contains(strings.ToLower(a.name), name) {
+			continue
+		}
+		artists = append(artists, sr.artist(a))
+	}
+
+	return paginate(artists, filters.Page, filters.PageSize), nil
+}
+
+// artist returns the artist along with the number of its songs not in the trash.
+func (sr *SongsRepository) artist(a *artistRecord) *model.Artist {
+	out := &model.Artist{ID: a.id, Name: a.name}
+	for _, s := range sr.songs {
+		if s.info.ArtistID == a.id && s.deletedAt.IsZero() {
+			out.TotalSongs++
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// GetStaleSongs returns up to limit songs due to be enriched again: never
+// enriched, enriched before staleBefore, or missing some details and enriched
+// before incompleteBefore. The songs that have failed to be enriched after
+// failedBefore are left out. The songs enriched the longest ago come first.
+func (sr *SongsRepository) GetStaleSongs(ctx context.Context, staleBefore time.Time, incompleteBefore time.Time, failedBefore time.Time, limit int) ([]uint64, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	stale := []*songRecord{}
+	for _, id := range sortedIDs(sr.songs) {
+		s, ok := sr.live(id)
+		if !ok || s.failedAt.After(failedBefore) {
+			continue
+		}
+
+		enrichedAt := s.info.EnrichedAt
+		incomplete := s.info.ReleaseDate == "" || s.info.Link == "" || len(s.verses) == 0
+		if enrichedAt == nil || enrichedAt.Before(staleBefore) || (incomplete && enrichedAt.Before(incompleteBefore)) {
+			stale = append(stale, s)
+		}
+	}
+
+	// never enriched first, the sort being stable keeps the songs in ID order
+	slices.SortStableFunc(stale, func(a, b *songRecord) int {
+		switch {
+		case a.info.EnrichedAt == nil && b.info.EnrichedAt == nil:
+			return 0
+		case a.info.EnrichedAt == nil:
+			return -1
+		case b.info.EnrichedAt == nil:
+			return 1
+		}
+		return a.info.EnrichedAt.Compare(*b.info.EnrichedAt)
+	})
+
+	ids := []uint64{}
+	for _, s := range stale[:min(len(stale), max(limit, 0))] {
+		ids = append(ids, s.info.ID)
+	}
+	return ids, nil
+}
+
+// SetEnrichedAt records when the song was enriched without changing it,
+// no revision is recorded.
+func (sr *SongsRepository) SetEnrichedAt(ctx context.Context, id uint64, at time.Time) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if s, ok := sr.live(id); ok {
+		s.info.EnrichedAt = &at
+	}
+	return nil
+}
+
+// SetEnrichmentFailedAt records when the song has failed to be enriched,
+// no revision is recorded.
+func (sr *SongsRepository) SetEnrichmentFailedAt(ctx context.Context, id uint64, at time.Time) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if s, ok := sr.live(id); ok {
+		s.failedAt = at
+	}
+	return nil
+}
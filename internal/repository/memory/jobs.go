@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+// InsertJob saves a new pending job.
+func (sr *SongsRepository) InsertJob(ctx context.Context, job *model.Job) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	sr.lastJobID++
+	job.ID = sr.lastJobID
+	job.Status = model.JobStatusPending
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+
+	stored := *job
+	sr.jobs[job.ID] = &stored
+	return nil
+}
+
+func (sr *SongsRepository) GetJob(ctx context.Context, id uint64) (*model.Job, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	job, ok := sr.jobs[id]
+	if !ok {
+		return nil, db.ErrRecordNotFound
+	}
+	out := *job
+	return &out, nil
+}
+
+// ClaimJob marks the oldest pending job as running and returns it,
+// ErrRecordNotFound is returned if no job is pending.
+func (sr *SongsRepository) ClaimJob(ctx context.Context) (*model.Job, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	for _, id := range sortedIDs(sr.jobs) {
+		job := sr.jobs[id]
+		if job.Status != model.JobStatusPending {
+			continue
+		}
+
+		job.Status = model.JobStatusRunning
+		job.UpdatedAt = time.Now()
+		job.Claims++
+		out := *job
+		return &out, nil
+	}
+	return nil, db.ErrRecordNotFound
+}
+
+// FinishJob saves the status and the outcome of the job. ErrEditConflict is
+// returned if the job has been requeued or claimed again since it was claimed.
+func (sr *SongsRepository) FinishJob(ctx context.Context, job *model.Job) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	stored, ok := sr.claimed(job)
+	if !ok {
+		return db.ErrEditConflict
+	}
+
+	job.UpdatedAt = time.Now()
+	stored.Status = job.Status
+	stored.Result = job.Result
+	stored.SongID = job.SongID
+	stored.Error = job.Error
+	stored.UpdatedAt = job.UpdatedAt
+	return nil
+}
+
+// RenewJob extends the lease of the running job, so that it is not requeued.
+// ErrEditConflict is returned if the job has been requeued or claimed again
+// since it was claimed.
+func (sr *SongsRepository) RenewJob(ctx context.Context, job *model.Job) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	stored, ok := sr.claimed(job)
+	if !ok {
+		return db.ErrEditConflict
+	}
+
+	job.UpdatedAt = time.Now()
+	stored.UpdatedAt = job.UpdatedAt
+	return nil
+}
+
+// claimed returns the stored job if it is still running under the claim of job.
+func (sr *SongsRepository) claimed(job *model.Job) (*model.Job, bool) {
+	stored, ok := sr.jobs[job.ID]
+	if !ok || stored.Status != model.JobStatusRunning || stored.Claims != job.Claims {
+		return nil, false
+	}
+	return stored, true
+}
+
+// RequeueJobs makes the running jobs whose lease has not been renewed since
+// runningBefore pending again and returns their number. Since nothing
+// survives a restart, there are none after a crash.
+func (sr *SongsRepository) RequeueJobs(ctx context.Context, runningBefore time.Time) (int64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	var requeued int64
+	for _, job := range sr.jobs {
+		if job.Status == model.JobStatusRunning && job.UpdatedAt.Before(runningBefore) {
+			job.Status = model.JobStatusPending
+			job.UpdatedAt = time.Now()
+			requeued++
+		}
+	}
+	return requeued, nil
+}
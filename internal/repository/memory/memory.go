@@ -0,0 +1,179 @@
+// Package memory implements the song storage in memory, so that the API can
+// be run without a database. It mirrors the behaviour of the Postgres
+// repository and returns the same errors. Nothing survives a restart.
+package memory
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/pkg/releasedate"
+)
+
+type SongsRepository struct {
+	mu sync.RWMutex
+
+	songs     map[uint64]*songRecord
+	artists   map[uint64]*artistRecord
+	albums    map[uint64]*albumRecord
+	playlists map[uint64]*playlistRecord
+	revisions map[uint64][]*model.SongRevision
+	jobs      map[uint64]*model.Job
+
+	lastSongID     uint64
+	lastArtistID   uint64
+	lastAlbumID    uint64
+	lastJobID      uint64
+	lastPlaylistID uint64
+	lastEntryID    uint64
+}
+
+type songRecord struct {
+	info      model.SongInfo
+	verses    []model.Verse
+	deletedAt time.Time
+	// failedAt is when the song has last failed to be enriched.
+	failedAt time.Time
+}
+
+type artistRecord struct {
+	id   uint64
+	name string
+}
+
+type albumRecord struct {
+	id          uint64
+	artistID    uint64
+	title       string
+	releaseDate string
+	tracks      []track
+}
+
+type track struct {
+	position uint
+	songID   uint64
+}
+
+// playlistRecord keeps the entries in their order, the position
+// of an entry is its index plus one.
+type playlistRecord struct {
+	id          uint64
+	name        string
+	description string
+	entries     []playlistEntry
+}
+
+type playlistEntry struct {
+	id     uint64
+	songID uint64
+}
+
+func NewSongsRepository() *SongsRepository {
+	return &SongsRepository{
+		songs:     make(map[uint64]*songRecord),
+		artists:   make(map[uint64]*artistRecord),
+		albums:    make(map[uint64]*albumRecord),
+		playlists: make(map[uint64]*playlistRecord),
+		revisions: make(map[uint64][]*model.SongRevision),
+		jobs:      make(map[uint64]*model.Job),
+	}
+}
+
+// normalizeName trims the name and collapses inner whitespace,
+// so that "Muse" and " Muse " resolve to the same artist.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// upsertArtist resolves an artist by its case-insensitive name, creating it
+// if it does not exist yet. The caller must hold the write lock.
+func (sr *SongsRepository) upsertArtist(name string) *artistRecord {
+	name = normalizeName(name)
+	for _, a := range sr.artists {
+		if strings.EqualFold(a.name, name) {
+			return a
+		}
+	}
+
+	sr.lastArtistID++
+	a := &artistRecord{id: sr.lastArtistID, name: name}
+	sr.artists[a.id] = a
+	return a
+}
+
+// titleKey is the key song titles are unique by within a group.
+func titleKey(title string) string {
+	return strings.ToLower(normalizeName(title))
+}
+
+// duplicateOf returns the live song of the artist with the same title as the
+// given one, other than the song itself. The caller must hold the lock.
+func (sr *SongsRepository) duplicateOf(artistID uint64, title string, songID uint64) (*songRecord, bool) {
+	key := titleKey(title)
+	for _, id := range sortedIDs(sr.songs) {
+		s, ok := sr.live(id)
+		if ok && id != songID && s.info.ArtistID == artistID && titleKey(s.info.Song) == key {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// live returns the song if it exists and is not in the trash.
+func (sr *SongsRepository) live(id uint64) (*songRecord, bool) {
+	s, ok := sr.songs[id]
+	if !ok || !s.deletedAt.IsZero() {
+		return nil, false
+	}
+	return s, true
+}
+
+// songInfo returns a copy of the song with its group name resolved.
+func (sr *SongsRepository) songInfo(s *songRecord) *model.SongInfo {
+	info := s.info
+	info.Group = sr.artists[info.ArtistID].name
+	info.Text = slices.Clone(info.Text)
+	info.Sources = maps.Clone(info.Sources)
+	return &info
+}
+
+// canonicalReleaseDate formats the release date the way it is read back
+// from the database: unparseable dates are dropped.
+func canonicalReleaseDate(value string) string {
+	t, p, err := releasedate.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return releasedate.Format(t, p)
+}
+
+// period returns the interval covered by the release date, ok is false
+// for songs without one.
+func period(value string) (from time.Time, to time.Time, ok bool) {
+	from, to, err := releasedate.ParsePeriod(value)
+	return from, to, err == nil
+}
+
+// sortedIDs returns the keys of the map in ascending order.
+func sortedIDs[T any](m map[uint64]T) []uint64 {
+	ids := make([]uint64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// paginate returns the page of the items.
+func paginate[T any](items []T, page uint, pageSize uint) []T {
+	offset := int((page - 1) * pageSize)
+	if offset >= len(items) {
+		return items[:0]
+	}
+	end := min(offset+int(pageSize), len(items))
+	return items[offset:end]
+}
@@ -0,0 +1,185 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetPlaylist(ctx context.Context, id uint64) (*model.Playlist, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	p, ok := sr.playlists[id]
+	if !ok {
+		return nil, db.ErrRecordNotFound
+	}
+
+	playlist := &model.Playlist{
+		ID:          p.id,
+		Name:        p.name,
+		Description: p.description,
+		Entries:     []model.PlaylistEntry{},
+	}
+	for i, e := range p.entries {
+		s := sr.songs[e.songID]
+		playlist.Entries = append(playlist.Entries, model.PlaylistEntry{
+			ID:       e.id,
+			Position: uint(i + 1),
+			Song: model.SongOut{
+				ID:          s.info.ID,
+				Group:       sr.artists[s.info.ArtistID].name,
+				Song:        s.info.Song,
+				ReleaseDate: s.info.ReleaseDate,
+				Link:        s.info.Link,
+				TotalVerses: uint(len(s.info.Text)),
+			},
+		})
+	}
+	return playlist, nil
+}
+
+func (sr *SongsRepository) GetPlaylists(ctx context.Context, filters model.PlaylistFilters) ([]*model.PlaylistOut, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	name := strings.ToLower(filters.Name)
+
+	playlists := []*model.PlaylistOut{}
+	for _, id := range sortedIDs(sr.playlists) {
+		p := sr.playlists[id]
+		if name != "" && !strings.Contains(strings.ToLower(p.name), name) {
+			continue
+		}
+
+		playlists = append(playlists, &model.PlaylistOut{
+			ID:           p.id,
+			Name:         p.name,
+			Description:  p.description,
+			TotalEntries: uint(len(p.entries)),
+		})
+	}
+
+	return paginate(playlists, filters.Page, filters.PageSize), nil
+}
+
+func (sr *SongsRepository) InsertPlaylist(ctx context.Context, playlist *model.Playlist) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	sr.lastPlaylistID++
+	playlist.ID = sr.lastPlaylistID
+
+	sr.playlists[playlist.ID] = &playlistRecord{
+		id:          playlist.ID,
+		name:        playlist.Name,
+		description: playlist.Description,
+	}
+	return nil
+}
+
+func (sr *SongsRepository) UpdatePlaylist(ctx context.Context, playlist *model.Playlist) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	p, ok := sr.playlists[playlist.ID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+
+	p.name = playlist.Name
+	p.description = playlist.Description
+	return nil
+}
+
+func (sr *SongsRepository) DeletePlaylist(ctx context.Context, id uint64) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if _, ok := sr.playlists[id]; !ok {
+		return db.ErrRecordNotFound
+	}
+	delete(sr.playlists, id)
+	return nil
+}
+
+// AddPlaylistEntry puts the song in the playlist at the given position,
+// shifting the following entries down. A zero or too large position appends
+// the song to the end of the playlist.
+func (sr *SongsRepository) AddPlaylistEntry(ctx context.Context, playlistID uint64, entry model.PlaylistEntryInput) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	p, ok := sr.playlists[playlistID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+	if _, ok := sr.live(entry.SongID); !ok {
+		return db.ErrRecordNotFound
+	}
+
+	total := uint(len(p.entries))
+	position := entry.Position
+	if position == 0 || position > total+1 {
+		position = total + 1
+	}
+
+	sr.lastEntryID++
+	p.entries = slices.Insert(p.entries, int(position-1), playlistEntry{id: sr.lastEntryID, songID: entry.SongID})
+	return nil
+}
+
+// MovePlaylistEntry moves the entry to the given position, shifting the
+// entries in between. A too large position moves it to the end of the playlist.
+func (sr *SongsRepository) MovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64, position uint) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	p, ok := sr.playlists[playlistID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+
+	i := p.entryIndex(entryID)
+	if i < 0 {
+		return db.ErrRecordNotFound
+	}
+
+	e := p.entries[i]
+	p.entries = slices.Delete(p.entries, i, i+1)
+	position = min(position, uint(len(p.entries))+1)
+	p.entries = slices.Insert(p.entries, int(position-1), e)
+	return nil
+}
+
+func (sr *SongsRepository) RemovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	p, ok := sr.playlists[playlistID]
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+
+	i := p.entryIndex(entryID)
+	if i < 0 {
+		return db.ErrRecordNotFound
+	}
+	p.entries = slices.Delete(p.entries, i, i+1)
+	return nil
+}
+
+func (p *playlistRecord) entryIndex(entryID uint64) int {
+	return slices.IndexFunc(p.entries, func(e playlistEntry) bool { return e.id == entryID })
+}
+
+// removeSongEntries removes the song from all the playlists.
+// The caller must hold the write lock.
+func (sr *SongsRepository) removeSongEntries(songID uint64) {
+	for _, p := range sr.playlists {
+		p.entries = slices.DeleteFunc(p.entries, func(e playlistEntry) bool { return e.songID == songID })
+	}
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetRevisions(ctx context.Context, filters model.SongRevisionFilters) ([]*model.SongRevisionOut, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	revisions := slices.Clone(sr.revisions[filters.SongID])
+	slices.Reverse(revisions)
+
+	out := []*model.SongRevisionOut{}
+	for _, rev := range paginate(revisions, filters.Page, filters.PageSize) {
+		out = append(out, &model.SongRevisionOut{
+			Revision:     rev.Revision,
+			Action:       rev.Action,
+			RevertedFrom: rev.RevertedFrom,
+			Editor:       rev.Editor,
+			CreatedAt:    rev.CreatedAt,
+			TotalVerses:  uint(len(rev.Text)),
+		})
+	}
+	return out, nil
+}
+
+func (sr *SongsRepository) GetRevision(ctx context.Context, songID uint64, revision uint) (*model.SongRevision, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	for _, rev := range sr.revisions[songID] {
+		if rev.Revision == revision {
+			out := *rev
+			out.Text = slices.Clone(rev.Text)
+			return &out, nil
+		}
+	}
+	return nil, db.ErrRecordNotFound
+}
+
+// insertRevision stores the snapshot of the song as the revision
+// with the number of its current version. The caller must hold the write lock.
+func (sr *SongsRepository) insertRevision(song *model.SongInfo, action string, change model.SongChange) {
+	sr.revisions[song.ID] = append(sr.revisions[song.ID], &model.SongRevision{
+		SongID:       song.ID,
+		Revision:     song.Version,
+		Group:        song.Group,
+		Song:         song.Song,
+		ReleaseDate:  song.ReleaseDate,
+		Text:         slices.Clone(song.Text),
+		Link:         song.Link,
+		Language:     song.Language,
+		Action:       action,
+		RevertedFrom: change.RevertedFrom,
+		Editor:       change.Editor,
+		CreatedAt:    time.Now(),
+	})
+}
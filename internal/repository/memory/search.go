@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/textsearch"
+)
+
+// Search runs a ranked search over song titles and lyrics, see package
+// textsearch for how it differs from the Postgres full-text search.
+func (sr *SongsRepository) Search(ctx context.Context, filters model.SongSearchFilters) ([]*model.SongSearchResult, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	query := textsearch.Parse(filters.Query)
+
+	results := []*model.SongSearchResult{}
+	for _, id := range sortedIDs(sr.songs) {
+		s, ok := sr.live(id)
+		if !ok || (filters.Language != "" && s.info.Language != filters.Language) {
+			continue
+		}
+
+		m, ok := query.Match(s.info.Song, s.info.Text)
+		if !ok {
+			continue
+		}
+
+		results = append(results, &model.SongSearchResult{
+			ID:      s.info.ID,
+			Group:   sr.artists[s.info.ArtistID].name,
+			Song:    s.info.Song,
+			Rank:    m.Rank,
+			Snippet: m.Snippet,
+			Verses:  m.Verses,
+		})
+	}
+
+	slices.SortStableFunc(results, func(a, b *model.SongSearchResult) int {
+		switch {
+		case a.Rank > b.Rank:
+			return -1
+		case a.Rank < b.Rank:
+			return 1
+		}
+		return 0
+	})
+
+	return paginate(results, filters.Page, filters.PageSize), nil
+}
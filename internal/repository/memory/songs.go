@@ -0,0 +1,498 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/internal/repository/keyset"
+	"effective-mobile-song-library/pkg/trigram"
+)
+
+// orderColumn is a sort key of the song listing. NULL values are always ordered last.
+type orderColumn struct {
+	key  string
+	desc bool
+}
+
+// songRow is a song of the listing along with the values of its sort keys.
+type songRow struct {
+	out  *model.SongOut
+	keys []*string
+}
+
+func (sr *SongsRepository) Get(ctx context.Context, id uint64) (*model.SongInfo, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	s, ok := sr.live(id)
+	if !ok {
+		return nil, db.ErrRecordNotFound
+	}
+	return sr.songInfo(s), nil
+}
+
+// GetByTitle returns the song of the group with the given title, compared
+// the way the uniqueness of songs is enforced.
+func (sr *SongsRepository) GetByTitle(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	for _, a := range sr.artists {
+		if !strings.EqualFold(a.name, normalizeName(group)) {
+			continue
+		}
+		if s, ok := sr.duplicateOf(a.id, song, 0); ok {
+			return sr.songInfo(s), nil
+		}
+	}
+	return nil, db.ErrRecordNotFound
+}
+
+// GetAll returns a page of songs along with the pagination metadata, or the
+// cursors of the neighbouring pages in keyset mode, like the Postgres repository.
+func (sr *SongsRepository) GetAll(ctx context.Context, filters model.SongFilters) (*model.Songs, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	order := songOrder(filters.Sort)
+
+	rows := []songRow{}
+	for _, id := range sortedIDs(sr.songs) {
+		s, ok := sr.live(id)
+		if !ok {
+			continue
+		}
+		score, ok := sr.matches(s, filters)
+		if !ok {
+			continue
+		}
+
+		out := &model.SongOut{
+			ID:          s.info.ID,
+			Group:       sr.artists[s.info.ArtistID].name,
+			Song:        s.info.Song,
+			ReleaseDate: s.info.ReleaseDate,
+			Link:        s.info.Link,
+			TotalVerses: uint(len(s.info.Text)),
+			Score:       score,
+		}
+		rows = append(rows, songRow{out: out, keys: sortKeys(order, out)})
+	}
+
+	if filters.Sort == "" && filters.Match == model.MatchFuzzy {
+		slices.SortStableFunc(rows, func(a, b songRow) int {
+			return compareScores(a.out.Score, b.out.Score)
+		})
+	} else {
+		slices.SortStableFunc(rows, func(a, b songRow) int {
+			return compareKeys(order, a.keys, b.keys)
+		})
+	}
+
+	if !filters.Keyset {
+		metadata := model.CalculateMetadata(uint(len(rows)), filters.Page, filters.PageSize)
+		page := paginate(rows, filters.Page, filters.PageSize)
+
+		songs := make([]*model.SongOut, 0, len(page))
+		for _, row := range page {
+			songs = append(songs, row.out)
+		}
+		return &model.Songs{Songs: songs, Metadata: &metadata}, nil
+	}
+
+	return keysetPage(rows, order, filters)
+}
+
+// keysetPage cuts the page that starts after (or ends before) the row
+// the cursor points at out of the ordered rows.
+func keysetPage(rows []songRow, order []orderColumn, filters model.SongFilters) (*model.Songs, error) {
+	backward := filters.Before != ""
+	token := filters.After
+	if backward {
+		token = filters.Before
+	}
+
+	if token != "" {
+		keys, err := keyset.Decode(token, filters.Sort, cursorColumns(order))
+		if err != nil {
+			return nil, db.ErrInvalidCursor
+		}
+		rows = slices.DeleteFunc(rows, func(row songRow) bool {
+			cmp := compareKeys(order, row.keys, keys)
+			if backward {
+				return cmp >= 0
+			}
+			return cmp <= 0
+		})
+	}
+
+	size := int(filters.PageSize)
+	more := len(rows) > size
+	if more {
+		if backward {
+			rows = rows[len(rows)-size:]
+		} else {
+			rows = rows[:size]
+		}
+	}
+
+	songs := make([]*model.SongOut, 0, len(rows))
+	for _, row := range rows {
+		songs = append(songs, row.out)
+	}
+
+	var first, last []*string
+	if len(rows) > 0 {
+		first, last = rows[0].keys, rows[len(rows)-1].keys
+	}
+	cursors := keyset.Cursors(filters.Sort, token, backward, more, first, last)
+
+	return &model.Songs{Songs: songs, Cursors: cursors}, nil
+}
+
+// matches applies the listing filters to the song. In fuzzy mode it also
+// returns the similarity score of the group and song names.
+func (sr *SongsRepository) matches(s *songRecord, f model.SongFilters) (*float64, bool) {
+	name := strings.ToLower(sr.artists[s.info.ArtistID].name)
+	title := strings.ToLower(s.info.Song)
+	group := strings.ToLower(normalizeName(f.Group))
+	songName := strings.ToLower(f.Song)
+
+	var score *float64
+	if f.Match == model.MatchFuzzy {
+		var sims []float64
+		if group != "" {
+			sim := trigram.Similarity(name, group)
+			if sim < *f.Threshold {
+				return nil, false
+			}
+			sims = append(sims, sim)
+		}
+		if songName != "" {
+			sim := trigram.Similarity(title, songName)
+			if sim < *f.Threshold {
+				return nil, false
+			}
+			sims = append(sims, sim)
+		}
+		if len(sims) > 0 {
+			var sum float64
+			for _, sim := range sims {
+				sum += sim
+			}
+			avg := sum / float64(len(sims))
+			score = &avg
+		}
+	} else {
+		if group != "" && name != group {
+			return nil, false
+		}
+		if songName != "" && title != songName {
+			return nil, false
+		}
+	}
+
+	from, to, dated := period(s.info.ReleaseDate)
+	if f.ReleaseDate != "" {
+		fFrom, fTo, _ := period(f.ReleaseDate)
+		if !dated || from.Before(fFrom) || to.After(fTo) {
+			return nil, false
+		}
+	}
+	if f.ReleasedFrom != "" {
+		fFrom, _, _ := period(f.ReleasedFrom)
+		if !dated || from.Before(fFrom) {
+			return nil, false
+		}
+	}
+	if f.ReleasedTo != "" {
+		_, fTo, _ := period(f.ReleasedTo)
+		if !dated || !from.Before(fTo) {
+			return nil, false
+		}
+	}
+
+	if f.Text != "" && !slices.ContainsFunc(s.info.Text, func(verse string) bool {
+		return strings.Contains(verse, f.Text)
+	}) {
+		return nil, false
+	}
+	if f.Link != "" && s.info.Link != f.Link {
+		return nil, false
+	}
+	if f.ArtistID != 0 && s.info.ArtistID != f.ArtistID {
+		return nil, false
+	}
+	if f.Album != "" && !sr.onAlbum(s.info.ID, f.Album) {
+		return nil, false
+	}
+
+	return score, true
+}
+
+// onAlbum reports whether the song is a track of an album with the given title.
+func (sr *SongsRepository) onAlbum(songID uint64, title string) bool {
+	for _, al := range sr.albums {
+		if !strings.EqualFold(al.title, title) {
+			continue
+		}
+		if slices.ContainsFunc(al.tracks, func(t track) bool { return t.songID == songID }) {
+			return true
+		}
+	}
+	return false
+}
+
+// songOrder returns the sort keys for a comma-separated list such as
+// "-releaseDate,group", a "-" prefix meaning descending. The song ID
+// breaks the remaining ties.
+func songOrder(sort string) []orderColumn {
+	var order []orderColumn
+
+	for _, key := range strings.Split(sort, ",") {
+		name := strings.TrimPrefix(key, "-")
+		if !slices.Contains([]string{"id", "group", "song", "releaseDate"}, name) {
+			continue
+		}
+		order = append(order, orderColumn{key: name, desc: strings.HasPrefix(key, "-")})
+		if name == "id" {
+			// the ID is unique, later keys would never be compared
+			return order
+		}
+	}
+
+	return append(order, orderColumn{key: "id"})
+}
+
+// cursorColumns describes the sort keys for checking the cursor values.
+func cursorColumns(order []orderColumn) []keyset.Column {
+	columns := make([]keyset.Column, 0, len(order))
+	for _, col := range order {
+		switch col.key {
+		case "id":
+			columns = append(columns, keyset.Column{Kind: keyset.KindInteger})
+		case "releaseDate":
+			columns = append(columns, keyset.Column{Kind: keyset.KindDate, Nullable: true})
+		default:
+			columns = append(columns, keyset.Column{Kind: keyset.KindText})
+		}
+	}
+	return columns
+}
+
+// sortKeys returns the values of the sort keys of the song in the same textual
+// form the Postgres repository puts in its cursors, nil for NULL.
+func sortKeys(order []orderColumn, out *model.SongOut) []*string {
+	keys := make([]*string, 0, len(order))
+	for _, col := range order {
+		var value string
+		switch col.key {
+		case "id":
+			value = strconv.FormatUint(out.ID, 10)
+		case "group":
+			value = strings.ToLower(out.Group)
+		case "song":
+			value = strings.ToLower(out.Song)
+		case "releaseDate":
+			from, _, ok := period(out.ReleaseDate)
+			if !ok {
+				keys = append(keys, nil)
+				continue
+			}
+			value = from.Format(time.DateOnly)
+		}
+		keys = append(keys, &value)
+	}
+	return keys
+}
+
+func compareKeys(order []orderColumn, a []*string, b []*string) int {
+	for i, col := range order {
+		var cmp int
+		switch {
+		case a[i] == nil && b[i] == nil:
+			continue
+		case a[i] == nil:
+			return 1
+		case b[i] == nil:
+			return -1
+		case col.key == "id":
+			x, _ := strconv.ParseUint(*a[i], 10, 64)
+			y, _ := strconv.ParseUint(*b[i], 10, 64)
+			cmp = compareUint(x, y)
+		default:
+			cmp = strings.Compare(*a[i], *b[i])
+		}
+
+		if col.desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// compareScores orders by descending score with missing scores last,
+// the rows are already ordered by ID.
+func compareScores(a *float64, b *float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case *a > *b:
+		return -1
+	case *a < *b:
+		return 1
+	}
+	return 0
+}
+
+func compareUint(x uint64, y uint64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func (sr *SongsRepository) GetFullText(ctx context.Context, id uint64) (*string, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	var out string
+	if s, ok := sr.live(id); ok {
+		out = strings.Join(s.info.Text, "\n\n")
+	}
+	return &out, nil
+}
+
+func (sr *SongsRepository) GetText(ctx context.Context, filters model.SongTextFilters) (*string, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	var text string
+	if s, ok := sr.live(filters.ID); ok && filters.Verse >= 1 && int(filters.Verse) <= len(s.info.Text) {
+		text = s.info.Text[filters.Verse-1]
+	}
+	return &text, nil
+}
+
+// GetVerses returns the verses of the song with their types, ordered by position.
+func (sr *SongsRepository) GetVerses(ctx context.Context, id uint64) ([]model.Verse, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	verses := []model.Verse{}
+	if s, ok := sr.live(id); ok {
+		verses = append(verses, s.verses...)
+	}
+	return verses, nil
+}
+
+// Insert inserts the song and records its first revision.
+func (sr *SongsRepository) Insert(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	a := sr.upsertArtist(song.Group)
+	if existing, ok := sr.duplicateOf(a.id, song.Song, 0); ok {
+		return &db.DuplicateSongError{ID: existing.info.ID}
+	}
+
+	sr.lastSongID++
+	song.ID = sr.lastSongID
+	song.ArtistID = a.id
+	song.Group = a.name
+	song.ReleaseDate = canonicalReleaseDate(song.ReleaseDate)
+	song.Version = 1
+
+	sr.store(song)
+	sr.insertRevision(song, model.RevisionActionCreate, change)
+	return nil
+}
+
+// Update updates the song if its version has not changed since it was fetched
+// and records the new revision. ErrEditConflict is returned otherwise.
+func (sr *SongsRepository) Update(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	current, ok := sr.live(song.ID)
+	if !ok || current.info.Version != song.Version {
+		return db.ErrEditConflict
+	}
+
+	a := sr.upsertArtist(song.Group)
+	if existing, ok := sr.duplicateOf(a.id, song.Song, song.ID); ok {
+		return &db.DuplicateSongError{ID: existing.info.ID}
+	}
+
+	song.ArtistID = a.id
+	song.Group = a.name
+	song.ReleaseDate = canonicalReleaseDate(song.ReleaseDate)
+	song.Version++
+
+	sr.store(song)
+
+	action := model.RevisionActionUpdate
+	if change.RevertedFrom != 0 {
+		action = model.RevisionActionRevert
+	}
+	sr.insertRevision(song, action, change)
+	return nil
+}
+
+// store saves a copy of the song, keeping it out of the trash. Unless the
+// song carries its verses, they keep their types as long as their text is
+// the same.
+func (sr *SongsRepository) store(info *model.SongInfo) {
+	var previous []model.Verse
+	var failedAt time.Time
+	if s, ok := sr.songs[info.ID]; ok {
+		previous, failedAt = s.verses, s.failedAt
+	}
+
+	verses := slices.Clone(info.Verses)
+	if verses == nil {
+		verses = model.VersesFromText(info.Text, previous)
+	}
+
+	s := &songRecord{info: *info, verses: verses, failedAt: failedAt}
+	s.info.Text = slices.Clone(info.Text)
+	s.info.Sources = maps.Clone(info.Sources)
+	s.info.Verses = nil
+	sr.songs[info.ID] = s
+}
+
+// Delete moves the song to the trash. A non-zero version makes the deletion
+// conditional: ErrEditConflict is returned if the song has been changed since.
+// The song is removed from the playlists, restoring it does not put it back.
+func (sr *SongsRepository) Delete(ctx context.Context, id uint64, version uint) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	s, ok := sr.live(id)
+	if !ok {
+		return db.ErrRecordNotFound
+	}
+	if version != 0 && s.info.Version != version {
+		return db.ErrEditConflict
+	}
+
+	s.deletedAt = time.Now()
+	sr.removeSongEntries(id)
+	return nil
+}
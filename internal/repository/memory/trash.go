@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetTrash(ctx context.Context, filters model.TrashFilters) ([]*model.DeletedSongOut, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	songs := []*model.DeletedSongOut{}
+	for _, id := range sortedIDs(sr.songs) {
+		s := sr.songs[id]
+		if s.deletedAt.IsZero() {
+			continue
+		}
+		songs = append(songs, &model.DeletedSongOut{
+			ID:          s.info.ID,
+			Group:       sr.artists[s.info.ArtistID].name,
+			Song:        s.info.Song,
+			ReleaseDate: s.info.ReleaseDate,
+			DeletedAt:   s.deletedAt,
+		})
+	}
+
+	slices.SortStableFunc(songs, func(a, b *model.DeletedSongOut) int {
+		return b.DeletedAt.Compare(a.DeletedAt)
+	})
+
+	return paginate(songs, filters.Page, filters.PageSize), nil
+}
+
+// Restore takes the song out of the trash. DuplicateSongError is returned
+// if the group has got another song with the same title meanwhile.
+func (sr *SongsRepository) Restore(ctx context.Context, id uint64) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	s, ok := sr.songs[id]
+	if !ok || s.deletedAt.IsZero() {
+		return db.ErrRecordNotFound
+	}
+	if existing, ok := sr.duplicateOf(s.info.ArtistID, s.info.Song, id); ok {
+		return &db.DuplicateSongError{ID: existing.info.ID}
+	}
+	s.deletedAt = time.Time{}
+	return nil
+}
+
+// Purge permanently deletes the songs moved to the trash before the given time
+// along with their history and album tracks, and returns the number of deleted songs.
+func (sr *SongsRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	var purged int64
+	for id, s := range sr.songs {
+		if s.deletedAt.IsZero() || !s.deletedAt.Before(before) {
+			continue
+		}
+
+		delete(sr.songs, id)
+		delete(sr.revisions, id)
+		for _, al := range sr.albums {
+			al.removeTrack(id)
+		}
+		for _, job := range sr.jobs {
+			if job.SongID == id {
+				job.SongID = 0
+			}
+		}
+		purged++
+	}
+	return purged, nil
+}
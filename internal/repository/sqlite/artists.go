@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetArtist(ctx context.Context, id uint64) (*model.Artist, error) {
+	query := `
+	SELECT a.artist_id, a.name, COUNT(s.song_id)
+	FROM artists a
+	LEFT JOIN songs s ON s.artist_id = a.artist_id AND s.deleted_at IS NULL
+	WHERE a.artist_id=?1
+	GROUP BY a.artist_id`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var artist model.Artist
+
+	err := sr.db.QueryRowContext(ctx, query, id).Scan(
+		&artist.ID,
+		&artist.Name,
+		&artist.TotalSongs,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, db.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &artist, nil
+}
+
+func (sr *SongsRepository) GetArtists(ctx context.Context, filters model.ArtistFilters) ([]*model.Artist, error) {
+	query := `
+	SELECT a.artist_id, a.name, COUNT(s.song_id)
+	FROM artists a
+	LEFT JOIN songs s ON s.artist_id = a.artist_id AND s.deleted_at IS NULL
+	WHERE (?1 = '' OR instr(a.name_key, ?1) > 0)
+	GROUP BY a.artist_id
+	ORDER BY a.artist_id ASC
+	LIMIT ?2 OFFSET ?3`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{
+		nameKey(filters.Name),
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artists := []*model.Artist{}
+
+	for rows.Next() {
+		var artist model.Artist
+		err := rows.Scan(
+			&artist.ID,
+			&artist.Name,
+			&artist.TotalSongs,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		artists = append(artists, &artist)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return artists, nil
+}
@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+// InsertJob saves a new pending job.
+func (sr *SongsRepository) InsertJob(ctx context.Context, job *model.Job) error {
+	query := `
+	INSERT INTO jobs ("group", song, editor, on_conflict, created_at, updated_at)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?5)
+	RETURNING job_id`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	err := sr.db.QueryRowContext(ctx, query, job.Group, job.Song, job.Editor, job.OnConflict, timestamp(now)).Scan(&job.ID)
+	if err != nil {
+		return err
+	}
+
+	job.Status = model.JobStatusPending
+	job.CreatedAt, job.UpdatedAt = now, now
+	return nil
+}
+
+func (sr *SongsRepository) GetJob(ctx context.Context, id uint64) (*model.Job, error) {
+	query := `
+	SELECT job_id, status, "group", song, editor, on_conflict, result, COALESCE(song_id, 0), error, created_at, updated_at, claims
+	FROM jobs
+	WHERE job_id = ?1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var job model.Job
+	err := sr.db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID,
+		&job.Status,
+		&job.Group,
+		&job.Song,
+		&job.Editor,
+		&job.OnConflict,
+		&job.Result,
+		&job.SongID,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.Claims,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, db.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &job, nil
+}
+
+// ClaimJob marks the oldest pending job as running and returns it,
+// ErrRecordNotFound is returned if no job is pending. SQLite runs one write
+// at a time, so concurrent workers never claim the same job.
+func (sr *SongsRepository) ClaimJob(ctx context.Context) (*model.Job, error) {
+	query := `
+	UPDATE jobs
+	SET status = 'running', updated_at = ?1, claims = claims + 1
+	WHERE job_id = (
+		SELECT job_id
+		FROM jobs
+		WHERE status = 'pending'
+		ORDER BY job_id
+		LIMIT 1
+	)
+	RETURNING job_id`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var id uint64
+	err := sr.db.QueryRowContext(ctx, query, timestamp(time.Now())).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, db.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return sr.GetJob(ctx, id)
+}
+
+// FinishJob saves the status and the outcome of the job. ErrEditConflict is
+// returned if the job has been requeued or claimed again since it was claimed.
+func (sr *SongsRepository) FinishJob(ctx context.Context, job *model.Job) error {
+	query := `
+	UPDATE jobs
+	SET status = ?2, result = ?3, song_id = NULLIF(?4, 0), error = ?5, updated_at = ?6
+	WHERE job_id = ?1 AND status = 'running' AND claims = ?7`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	result, err := sr.db.ExecContext(ctx, query, job.ID, job.Status, job.Result, int64(job.SongID), job.Error, timestamp(now), job.Claims)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return db.ErrEditConflict
+	}
+
+	job.UpdatedAt = now
+	return nil
+}
+
+// RenewJob extends the lease of the running job, so that it is not requeued.
+// ErrEditConflict is returned if the job has been requeued or claimed again
+// since it was claimed.
+func (sr *SongsRepository) RenewJob(ctx context.Context, job *model.Job) error {
+	query := `
+	UPDATE jobs
+	SET updated_at = ?3
+	WHERE job_id = ?1 AND status = 'running' AND claims = ?2`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	result, err := sr.db.ExecContext(ctx, query, job.ID, job.Claims, timestamp(now))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return db.ErrEditConflict
+	}
+
+	job.UpdatedAt = now
+	return nil
+}
+
+// RequeueJobs makes the jobs left running, e.g. by a crash, pending again
+// and returns their number. Only the jobs whose lease has not been renewed
+// since runningBefore are requeued, the others are still being run.
+func (sr *SongsRepository) RequeueJobs(ctx context.Context, runningBefore time.Time) (int64, error) {
+	query := `
+	UPDATE jobs
+	SET status = 'pending', updated_at = ?1
+	WHERE status = 'running' AND updated_at < ?2`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	result, err := sr.db.ExecContext(ctx, query, timestamp(time.Now()), timestamp(runningBefore))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,391 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetPlaylist(ctx context.Context, id uint64) (*model.Playlist, error) {
+	query := `
+	SELECT playlist_id, name, description
+	FROM playlists
+	WHERE playlist_id=?1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var playlist model.Playlist
+
+	err := sr.db.QueryRowContext(ctx, query, id).Scan(
+		&playlist.ID,
+		&playlist.Name,
+		&playlist.Description,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, db.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	entriesQuery := `
+	SELECT e.entry_id, e.position, s.song_id, a.name, s.song, s.release_date, s.release_precision, COALESCE(s.link, ''),
+		(SELECT COUNT(*) FROM song_verses v WHERE v.song_id = s.song_id)
+	FROM playlist_entries e
+	JOIN songs s ON s.song_id = e.song_id
+	JOIN artists a ON a.artist_id = s.artist_id
+	WHERE e.playlist_id=?1
+	ORDER BY e.position ASC`
+
+	rows, err := sr.db.QueryContext(ctx, entriesQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlist.Entries = []model.PlaylistEntry{}
+
+	for rows.Next() {
+		var entry model.PlaylistEntry
+		var rd releaseDate
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Position,
+			&entry.Song.ID,
+			&entry.Song.Group,
+			&entry.Song.Song,
+			&rd.date,
+			&rd.precision,
+			&entry.Song.Link,
+			&entry.Song.TotalVerses,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.Song.ReleaseDate = rd.String()
+
+		playlist.Entries = append(playlist.Entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &playlist, nil
+}
+
+func (sr *SongsRepository) GetPlaylists(ctx context.Context, filters model.PlaylistFilters) ([]*model.PlaylistOut, error) {
+	query := `
+	SELECT p.playlist_id, p.name, p.description, COUNT(e.entry_id)
+	FROM playlists p
+	LEFT JOIN playlist_entries e ON e.playlist_id = p.playlist_id
+	WHERE (?1 = '' OR instr(lower_utf8(p.name), lower_utf8(?1)) > 0)
+	GROUP BY p.playlist_id
+	ORDER BY p.playlist_id ASC
+	LIMIT ?2 OFFSET ?3`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{
+		filters.Name,
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	playlists := []*model.PlaylistOut{}
+
+	for rows.Next() {
+		var playlist model.PlaylistOut
+		err := rows.Scan(
+			&playlist.ID,
+			&playlist.Name,
+			&playlist.Description,
+			&playlist.TotalEntries,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		playlists = append(playlists, &playlist)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return playlists, nil
+}
+
+func (sr *SongsRepository) InsertPlaylist(ctx context.Context, playlist *model.Playlist) error {
+	query := `
+	INSERT INTO playlists (name, description)
+	VALUES (?1, ?2)
+	RETURNING playlist_id`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	return sr.db.QueryRowContext(ctx, query, playlist.Name, playlist.Description).Scan(&playlist.ID)
+}
+
+func (sr *SongsRepository) UpdatePlaylist(ctx context.Context, playlist *model.Playlist) error {
+	query := `
+	UPDATE playlists
+	SET name = ?1, description = ?2
+	WHERE playlist_id = ?3`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	result, err := sr.db.ExecContext(ctx, query, playlist.Name, playlist.Description, playlist.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return db.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (sr *SongsRepository) DeletePlaylist(ctx context.Context, id uint64) error {
+	query := `
+	DELETE FROM playlists
+	WHERE playlist_id = ?1`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	result, err := sr.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return db.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AddPlaylistEntry puts the song in the playlist at the given position,
+// shifting the following entries down. A zero or too large position appends
+// the song to the end of the playlist.
+func (sr *SongsRepository) AddPlaylistEntry(ctx context.Context, playlistID uint64, entry model.PlaylistEntryInput) error {
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	// write transactions are serialized by the database lock,
+	// so the positions cannot be changed concurrently
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = playlistExists(ctx, tx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM songs WHERE song_id = ?1 AND deleted_at IS NULL)`, entry.SongID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return db.ErrRecordNotFound
+	}
+
+	var total uint
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM playlist_entries WHERE playlist_id = ?1`, playlistID).Scan(&total)
+	if err != nil {
+		return err
+	}
+
+	position := entry.Position
+	if position == 0 || position > total+1 {
+		position = total + 1
+	}
+
+	shiftQuery := `
+	UPDATE playlist_entries
+	SET position = position + 1
+	WHERE playlist_id = ?1 AND position >= ?2`
+
+	_, err = tx.ExecContext(ctx, shiftQuery, playlistID, position)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := `
+	INSERT INTO playlist_entries (playlist_id, song_id, position)
+	VALUES (?1, ?2, ?3)`
+
+	_, err = tx.ExecContext(ctx, insertQuery, playlistID, entry.SongID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MovePlaylistEntry moves the entry to the given position, shifting the
+// entries in between. A too large position moves it to the end of the playlist.
+func (sr *SongsRepository) MovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64, position uint) error {
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = playlistExists(ctx, tx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	var current, total uint
+	query := `
+	SELECT position, (SELECT COUNT(*) FROM playlist_entries WHERE playlist_id = ?1)
+	FROM playlist_entries
+	WHERE playlist_id = ?1 AND entry_id = ?2`
+
+	err = tx.QueryRowContext(ctx, query, playlistID, entryID).Scan(&current, &total)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return db.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	position = min(position, total)
+	if position == current {
+		return nil
+	}
+
+	from, to, shift := position, current-1, 1
+	if position > current {
+		from, to, shift = current+1, position, -1
+	}
+
+	moveQuery := `
+	UPDATE playlist_entries
+	SET position = CASE WHEN entry_id = ?2 THEN ?3 ELSE position + ?4 END
+	WHERE playlist_id = ?1 AND (entry_id = ?2 OR position BETWEEN ?5 AND ?6)`
+
+	_, err = tx.ExecContext(ctx, moveQuery, playlistID, entryID, position, shift, from, to)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (sr *SongsRepository) RemovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = playlistExists(ctx, tx, playlistID)
+	if err != nil {
+		return err
+	}
+
+	deleteQuery := `
+	DELETE FROM playlist_entries
+	WHERE playlist_id = ?1 AND entry_id = ?2
+	RETURNING position`
+
+	var position uint
+	err = tx.QueryRowContext(ctx, deleteQuery, playlistID, entryID).Scan(&position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return db.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	shiftQuery := `
+	UPDATE playlist_entries
+	SET position = position - 1
+	WHERE playlist_id = ?1 AND position > ?2`
+
+	_, err = tx.ExecContext(ctx, shiftQuery, playlistID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// playlistExists returns ErrRecordNotFound if there is no such playlist.
+func playlistExists(ctx context.Context, tx *sql.Tx, playlistID uint64) error {
+	var id uint64
+	err := tx.QueryRowContext(ctx, `SELECT playlist_id FROM playlists WHERE playlist_id = ?1`, playlistID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return db.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// removeSongEntries removes the song from all the playlists and closes the
+// gaps left in their positions.
+func removeSongEntries(ctx context.Context, tx *sql.Tx, songID uint64) error {
+	renumberQuery := `
+	UPDATE playlist_entries AS e
+	SET position = r.position
+	FROM (
+		SELECT entry_id, ROW_NUMBER() OVER (PARTITION BY playlist_id ORDER BY position) AS position
+		FROM playlist_entries
+		WHERE song_id <> ?1
+		AND playlist_id IN (SELECT playlist_id FROM playlist_entries WHERE song_id = ?1)
+	) r
+	WHERE e.entry_id = r.entry_id AND e.position <> r.position`
+
+	_, err := tx.ExecContext(ctx, renumberQuery, songID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM playlist_entries WHERE song_id = ?1`, songID)
+	return err
+}
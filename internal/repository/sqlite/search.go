@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/textsearch"
+)
+
+// Search runs a ranked search over song titles and lyrics. SQLite has no
+// stemming full-text search built in, so the songs are matched in Go, see
+// package textsearch for how it differs from the Postgres search.
+func (sr *SongsRepository) Search(ctx context.Context, filters model.SongSearchFilters) ([]*model.SongSearchResult, error) {
+	query := `
+	SELECT s.song_id, a.name, s.song, ` + songTextQuery + `
+	FROM songs s
+	JOIN artists a ON a.artist_id = s.artist_id
+	WHERE s.deleted_at IS NULL AND (?1 = '' OR s.language = ?1)
+	ORDER BY s.song_id ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	rows, err := sr.db.QueryContext(ctx, query, filters.Language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	q := textsearch.Parse(filters.Query)
+	results := []*model.SongSearchResult{}
+
+	for rows.Next() {
+		var result model.SongSearchResult
+		var text []string
+		err := rows.Scan(
+			&result.ID,
+			&result.Group,
+			&result.Song,
+			(*verses)(&text),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := q.Match(result.Song, text)
+		if !ok {
+			continue
+		}
+		result.Rank = m.Rank
+		result.Snippet = m.Snippet
+		result.Verses = m.Verses
+
+		results = append(results, &result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(results, func(a, b *model.SongSearchResult) int {
+		switch {
+		case a.Rank > b.Rank:
+			return -1
+		case a.Rank < b.Rank:
+			return 1
+		}
+		return 0
+	})
+
+	offset := min(int((filters.Page-1)*filters.PageSize), len(results))
+	end := min(offset+int(filters.PageSize), len(results))
+	return results[offset:end], nil
+}
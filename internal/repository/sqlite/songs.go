@@ -0,0 +1,565 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/internal/repository/keyset"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func (sr *SongsRepository) Get(ctx context.Context, id uint64) (*model.SongInfo, error) {
+	return sr.getSong(ctx, `s.song_id=?1`, id)
+}
+
+// GetByTitle returns the song of the group with the given title, compared
+// the way the uniqueness of songs is enforced.
+func (sr *SongsRepository) GetByTitle(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	return sr.getSong(ctx, `a.name_key=?1 AND s.song_key=?2`, nameKey(group), nameKey(song))
+}
+
+// getSong returns the song matching the condition that is not in the trash.
+func (sr *SongsRepository) getSong(ctx context.Context, where string, args ...any) (*model.SongInfo, error) {
+	query := `
+	SELECT s.song_id, s.artist_id, a.name, s.song, s.release_date, s.release_precision, ` + songTextQuery + `, COALESCE(s.link, ''), s.language, s.version, s.sources, s.enriched_at
+	FROM songs s
+	JOIN artists a ON a.artist_id = s.artist_id
+	WHERE ` + where + ` AND s.deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var songInfo model.SongInfo
+	var rd releaseDate
+	var enrichedAt sql.NullTime
+
+	err := sr.db.QueryRowContext(ctx, query, args...).Scan(
+		&songInfo.ID,
+		&songInfo.ArtistID,
+		&songInfo.Group,
+		&songInfo.Song,
+		&rd.date,
+		&rd.precision,
+		(*verses)(&songInfo.Text),
+		&songInfo.Link,
+		&songInfo.Language,
+		&songInfo.Version,
+		(*sources)(&songInfo.Sources),
+		&enrichedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, db.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	songInfo.ReleaseDate = rd.String()
+	if enrichedAt.Valid {
+		songInfo.EnrichedAt = &enrichedAt.Time
+	}
+
+	return &songInfo, nil
+}
+
+// duplicateSong turns the violation of the unique title index into the
+// error reporting the existing song of the group with the same title.
+func (sr *SongsRepository) duplicateSong(ctx context.Context, err error, group string, song string) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode != sqlite3.ErrConstraintUnique {
+		return err
+	}
+	existing, lookupErr := sr.GetByTitle(ctx, group, song)
+	if lookupErr != nil {
+		return err
+	}
+	return &db.DuplicateSongError{ID: existing.ID}
+}
+
+// songSortColumns maps the sort keys accepted by the API to the columns they
+// order by. Their textual values are the same as in the Postgres repository.
+var songSortColumns = map[string]keyset.Column{
+	"id":          {Expr: "s.song_id", Cast: "INTEGER", Kind: keyset.KindInteger},
+	"group":       {Expr: "a.name_key", Cast: "TEXT"},
+	"song":        {Expr: "lower_utf8(s.song)", Cast: "TEXT"},
+	"releaseDate": {Expr: "s.release_date", Cast: "TEXT", Kind: keyset.KindDate, Nullable: true},
+}
+
+// GetAll returns a page of songs along with the pagination metadata, the total
+// being counted by the same query. In keyset mode the page starts after (or ends
+// before) the row the cursor points at and the cursors of the neighbouring
+// pages are returned instead.
+func (sr *SongsRepository) GetAll(ctx context.Context, filters model.SongFilters) (*model.Songs, error) {
+	order := songOrder(filters.Sort)
+	backward := filters.Before != ""
+	limit := filters.PageSize
+	offset := (filters.Page - 1) * filters.PageSize
+	keysetClause := "TRUE"
+	var keysetArgs []any
+
+	token := filters.After
+	if backward {
+		token = filters.Before
+	}
+	if filters.Keyset {
+		if token != "" {
+			keys, err := keyset.Decode(token, filters.Sort, order)
+			if err != nil {
+				return nil, db.ErrInvalidCursor
+			}
+			keysetClause, keysetArgs = keysetCondition(order, keys, backward, 14)
+		}
+		// one more row tells whether there is a further page
+		limit++
+		offset = 0
+	}
+
+	orderClause := keyset.OrderBy(order, backward)
+	if filters.Sort == "" && filters.Match == model.MatchFuzzy {
+		orderClause = "score DESC NULLS LAST, s.song_id ASC"
+	}
+
+	groupCondition := "a.name_key=?1"
+	songCondition := "lower_utf8(s.song)=lower_utf8(?2)"
+	score := "NULL"
+	if filters.Match == model.MatchFuzzy {
+		groupCondition = "similarity(a.name_key, ?1) >= ?13"
+		songCondition = "similarity(lower_utf8(s.song), lower_utf8(?2)) >= ?13"
+		score = `CASE
+			WHEN ?1 <> '' AND ?2 <> '' THEN (similarity(a.name_key, ?1) + similarity(lower_utf8(s.song), lower_utf8(?2))) / 2
+			WHEN ?1 <> '' THEN similarity(a.name_key, ?1)
+			WHEN ?2 <> '' THEN similarity(lower_utf8(s.song), lower_utf8(?2))
+		END`
+	}
+
+	// The total is counted apart from the page, so that it is known past
+	// the last page too: an empty page is a single row without a song.
+	total := "SELECT COUNT(*) AS total FROM matched"
+	if filters.Keyset {
+		total = "SELECT 0 AS total"
+	}
+
+	query := fmt.Sprintf(`
+	WITH matched AS (
+		SELECT s.song_id, %s AS score
+		FROM songs s
+		JOIN artists a ON a.artist_id = s.artist_id
+		WHERE s.deleted_at IS NULL
+		AND (?1 = '' OR %s)
+		AND (?2 = '' OR %s)
+		AND (
+			?3 IS NULL OR
+			(
+				s.release_date >= ?3 AND
+				date(s.release_date, CASE s.release_precision
+					WHEN 'year' THEN '+1 year'
+					WHEN 'month' THEN '+1 month'
+					ELSE '+1 day'
+				END) <= ?4
+			)
+		)
+		AND (
+			?5 = '' OR
+			EXISTS (
+				SELECT 1
+				FROM song_verses v
+				WHERE v.song_id = s.song_id AND instr(v.text, ?5) > 0
+			)
+		)
+		AND (s.link=?6 OR ?6 = '')
+		AND (?7 = 0 OR s.artist_id = ?7)
+		AND (
+			?8 = '' OR
+			EXISTS (
+				SELECT 1
+				FROM album_tracks t
+				JOIN albums al ON al.album_id = t.album_id
+				WHERE t.song_id = s.song_id AND lower_utf8(al.title) = lower_utf8(?8)
+			)
+		)
+		AND (?9 IS NULL OR s.release_date >= ?9)
+		AND (?10 IS NULL OR s.release_date < ?10)
+	),
+	page AS (
+		SELECT s.song_id, a.name, s.song, s.release_date, s.release_precision, s.link,
+			(SELECT COUNT(*) FROM song_verses v WHERE v.song_id = s.song_id) AS total_verses, m.score, %s AS sort_keys,
+			ROW_NUMBER() OVER (ORDER BY %s) AS ord
+		FROM matched m
+		JOIN songs s ON s.song_id = m.song_id
+		JOIN artists a ON a.artist_id = s.artist_id
+		WHERE %s
+		ORDER BY %s
+		LIMIT ?11 OFFSET ?12
+	)
+	SELECT COALESCE(p.song_id, 0), COALESCE(p.name, ''), COALESCE(p.song, ''), p.release_date, p.release_precision, COALESCE(p.link, ''),
+		COALESCE(p.total_verses, 0), p.score, COALESCE(p.sort_keys, '[]'), t.total
+	FROM (%s) t
+	LEFT JOIN page p ON TRUE
+	ORDER BY p.ord`, score, groupCondition, songCondition, sortKeys(order), orderClause, keysetClause, orderClause, total)
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	releaseFrom, releaseTo := periodArgs(filters.ReleaseDate)
+	releasedFrom, _ := periodArgs(filters.ReleasedFrom)
+	_, releasedTo := periodArgs(filters.ReleasedTo)
+
+	args := []any{
+		nameKey(filters.Group),
+		filters.Song,
+		releaseFrom,
+		releaseTo,
+		filters.Text,
+		filters.Link,
+		filters.ArtistID,
+		filters.Album,
+		releasedFrom,
+		releasedTo,
+		limit,
+		offset,
+		filters.Threshold,
+	}
+	args = append(args, keysetArgs...)
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := []*model.SongOut{}
+	var keys [][]*string
+	var totalRecords uint
+
+	for rows.Next() {
+		var song model.SongOut
+		var rd releaseDate
+		var score sql.NullFloat64
+		var rowKeys string
+		err := rows.Scan(
+			&song.ID,
+			&song.Group,
+			&song.Song,
+			&rd.date,
+			&rd.precision,
+			&song.Link,
+			&song.TotalVerses,
+			&score,
+			&rowKeys,
+			&totalRecords,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if song.ID == 0 {
+			continue
+		}
+		song.ReleaseDate = rd.String()
+		if score.Valid {
+			song.Score = &score.Float64
+		}
+
+		var k []*string
+		err = json.Unmarshal([]byte(rowKeys), &k)
+		if err != nil {
+			return nil, err
+		}
+
+		songs = append(songs, &song)
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !filters.Keyset {
+		metadata := model.CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+		return &model.Songs{Songs: songs, Metadata: &metadata}, nil
+	}
+
+	more := uint(len(songs)) > filters.PageSize
+	if more {
+		songs = songs[:filters.PageSize]
+		keys = keys[:filters.PageSize]
+	}
+	if backward {
+		slices.Reverse(songs)
+		slices.Reverse(keys)
+	}
+
+	var first, last []*string
+	if len(keys) > 0 {
+		first, last = keys[0], keys[len(keys)-1]
+	}
+	cursors := keyset.Cursors(filters.Sort, token, backward, more, first, last)
+
+	return &model.Songs{Songs: songs, Cursors: cursors}, nil
+}
+
+// songOrder returns the columns ordering the songs for a comma-separated list
+// of sort keys such as "-releaseDate,group", a "-" prefix meaning descending.
+// Keys must be validated beforehand, unknown ones are skipped. The song ID
+// breaks the remaining ties.
+func songOrder(sort string) []keyset.Column {
+	var order []keyset.Column
+
+	for _, key := range strings.Split(sort, ",") {
+		column, ok := songSortColumns[strings.TrimPrefix(key, "-")]
+		if !ok {
+			continue
+		}
+		column.Desc = strings.HasPrefix(key, "-")
+		order = append(order, column)
+
+		if column.Expr == songSortColumns["id"].Expr {
+			// the ID is unique, later keys would never be compared
+			return order
+		}
+	}
+
+	return append(order, songSortColumns["id"])
+}
+
+// sortKeys renders the select expression returning the values of the sort
+// columns of a row as a JSON array of text, they are used to build the page cursors.
+func sortKeys(columns []keyset.Column) string {
+	keys := make([]string, 0, len(columns))
+	for _, col := range columns {
+		keys = append(keys, "CAST("+col.Expr+" AS TEXT)")
+	}
+	return "json_array(" + strings.Join(keys, ", ") + ")"
+}
+
+// keysetCondition builds the keyset condition for the cursor keys,
+// numbering the placeholders starting from next.
+func keysetCondition(columns []keyset.Column, keys []*string, backward bool, next int) (string, []any) {
+	var args []any
+	condition := keyset.Condition(columns, keys, backward, func(value string, cast string) string {
+		args = append(args, value)
+		return fmt.Sprintf("CAST(?%d AS %s)", next+len(args)-1, cast)
+	})
+	return condition, args
+}
+
+func (sr *SongsRepository) GetFullText(ctx context.Context, id uint64) (*string, error) {
+	query := `
+	SELECT ` + songTextQuery + `
+	FROM songs s
+	WHERE (s.song_id = ?1 AND s.deleted_at IS NULL)`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var fullText []string
+	err := sr.db.QueryRowContext(ctx, query, id).Scan((*verses)(&fullText))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	out := strings.Join(fullText, "\n\n")
+	return &out, nil
+}
+
+func (sr *SongsRepository) GetText(ctx context.Context, filters model.SongTextFilters) (*string, error) {
+	query := `
+	SELECT v.text
+	FROM song_verses v
+	JOIN songs s ON s.song_id = v.song_id
+	WHERE (v.song_id = ?1 AND v.position = ?2 AND s.deleted_at IS NULL)`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	var text string
+	err := sr.db.QueryRowContext(ctx, query, filters.ID, filters.Verse).Scan(&text)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return &text, nil
+}
+
+// Insert inserts the song and records its first revision.
+func (sr *SongsRepository) Insert(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
+	query := `
+	INSERT INTO songs (artist_id, song, release_date, release_precision, link, language, song_key, sources, enriched_at)
+	VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+	RETURNING song_id, version`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	song.ArtistID, song.Group, err = upsertArtist(ctx, tx, song.Group)
+	if err != nil {
+		return err
+	}
+
+	rd := newReleaseDate(song.ReleaseDate)
+
+	args := []any{
+		song.ArtistID,
+		song.Song,
+		rd.date,
+		rd.precision,
+		song.Link,
+		song.Language,
+		nameKey(song.Song),
+		sources(song.Sources),
+		nullTimestamp(song.EnrichedAt),
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&song.ID, &song.Version)
+	if err != nil {
+		return sr.duplicateSong(ctx, err, song.Group, song.Song)
+	}
+
+	err = setVerses(ctx, tx, song)
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(ctx, tx, song, model.RevisionActionCreate, change)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update updates the song if its version has not changed since it was fetched
+// and records the new revision. ErrEditConflict is returned otherwise.
+func (sr *SongsRepository) Update(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
+	query := `
+	UPDATE songs
+	SET artist_id = ?1, song = ?2, release_date = ?3, release_precision = ?4, link = ?5, language = ?6,
+		song_key = ?9, sources = ?10, enriched_at = ?11, version = version + 1
+	WHERE song_id = ?7 AND version = ?8 AND deleted_at IS NULL
+	RETURNING version`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	artistID, group, err := upsertArtist(ctx, tx, song.Group)
+	if err != nil {
+		return err
+	}
+
+	rd := newReleaseDate(song.ReleaseDate)
+
+	args := []any{
+		artistID,
+		song.Song,
+		rd.date,
+		rd.precision,
+		song.Link,
+		song.Language,
+		song.ID,
+		song.Version,
+		nameKey(song.Song),
+		sources(song.Sources),
+		nullTimestamp(song.EnrichedAt),
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&song.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return db.ErrEditConflict
+		default:
+			return sr.duplicateSong(ctx, err, group, song.Song)
+		}
+	}
+	song.ArtistID, song.Group = artistID, group
+
+	err = setVerses(ctx, tx, song)
+	if err != nil {
+		return err
+	}
+
+	action := model.RevisionActionUpdate
+	if change.RevertedFrom != 0 {
+		action = model.RevisionActionRevert
+	}
+
+	err = insertRevision(ctx, tx, song, action, change)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete moves the song to the trash. A non-zero version makes the deletion
+// conditional: ErrEditConflict is returned if the song has been changed since.
+// The song is removed from the playlists, restoring it does not put it back.
+func (sr *SongsRepository) Delete(ctx context.Context, id uint64, version uint) error {
+	query := `
+	UPDATE songs
+	SET deleted_at = ?3
+	WHERE song_id = ?1 AND deleted_at IS NULL AND (?2 = 0 OR version = ?2)`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, version, timestamp(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		if version == 0 {
+			return db.ErrRecordNotFound
+		}
+
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM songs WHERE song_id = ?1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return db.ErrEditConflict
+		}
+		return db.ErrRecordNotFound
+	}
+
+	err = removeSongEntries(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,208 @@
+// Package sqlite implements the song storage on top of an SQLite file, for
+// deployments without a Postgres server. It mirrors the behaviour of the
+// Postgres repository and returns the same errors. Revision lyrics are
+// stored as JSON arrays, dates as ISO 8601 text, and the full-text search
+// runs in Go.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"effective-mobile-song-library/pkg/releasedate"
+	"effective-mobile-song-library/pkg/trigram"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the database/sql driver the storage must be opened with,
+// it registers the SQL functions the queries rely on.
+const DriverName = "sqlite3_songs"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// the built-in LOWER only folds ASCII letters
+			err := conn.RegisterFunc("lower_utf8", strings.ToLower, true)
+			if err != nil {
+				return err
+			}
+			// the keys of the names, for the migrations to compute them
+			// as the repository does
+			err = conn.RegisterFunc("name_key", nameKey, true)
+			if err != nil {
+				return err
+			}
+			return conn.RegisterFunc("similarity", trigram.Similarity, true)
+		},
+	})
+}
+
+// DSN returns the data source name of the database file. Foreign keys are
+// enforced and write transactions take the database lock when they begin,
+// so that they are serialized instead of failing on upgrade.
+func DSN(path string) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_txlock", "immediate")
+	params.Set("_busy_timeout", "5000")
+	params.Set("_journal_mode", "WAL")
+	return "file:" + path + "?" + params.Encode()
+}
+
+type SongsRepository struct {
+	db *sql.DB
+	// timeout bounds every storage operation
+	timeout time.Duration
+}
+
+func NewSongsRepository(db *sql.DB, timeout time.Duration) *SongsRepository {
+	return &SongsRepository{db: db, timeout: timeout}
+}
+
+// normalizeName trims the name and collapses inner whitespace,
+// so that "Muse" and " Muse " resolve to the same artist.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// nameKey is the key artists are unique by: the normalized lowercase name.
+func nameKey(name string) string {
+	return strings.ToLower(normalizeName(name))
+}
+
+// upsertArtist resolves an artist by its case-insensitive name, creating it
+// if it does not exist yet.
+func upsertArtist(ctx context.Context, tx *sql.Tx, name string) (id uint64, stored string, err error) {
+	query := `
+	INSERT INTO artists (name, name_key)
+	VALUES (?1, ?2)
+	ON CONFLICT (name_key) DO UPDATE SET name = artists.name
+	RETURNING artist_id, name`
+
+	name = normalizeName(name)
+	err = tx.QueryRowContext(ctx, query, name, nameKey(name)).Scan(&id, &stored)
+	return id, stored, err
+}
+
+// verses is the JSON representation of the song lyrics, a nil slice
+// being stored as NULL.
+type verses []string
+
+func (v verses) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal([]string(v))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (v *verses) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), (*[]string)(v))
+	case []byte:
+		return json.Unmarshal(src, (*[]string)(v))
+	}
+	return fmt.Errorf("cannot scan %T into verses", src)
+}
+
+// sources is the JSON object naming the provider of every enriched field.
+type sources map[string]string
+
+func (s sources) Value() (driver.Value, error) {
+	if s == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]string(s))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *sources) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(src), (*map[string]string)(s))
+	case []byte:
+		return json.Unmarshal(src, (*map[string]string)(s))
+	}
+	return fmt.Errorf("cannot scan %T into sources", src)
+}
+
+// timestampLayout has a fixed width, so that timestamps compare as text.
+const timestampLayout = "2006-01-02 15:04:05.000000000"
+
+// timestamp formats the time the way timestamp columns store it.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// nullTimestamp formats the time like timestamp, nil being NULL.
+func nullTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return timestamp(*t)
+}
+
+// releaseDate is the database representation of a textual release date:
+// the first day of the period in the YYYY-MM-DD form and the precision
+// it is known with.
+type releaseDate struct {
+	date      sql.NullString
+	precision sql.NullString
+}
+
+// newReleaseDate converts the textual release date. Empty or unparseable
+// values are stored as NULL.
+func newReleaseDate(value string) releaseDate {
+	t, p, err := releasedate.Parse(value)
+	if err != nil {
+		return releaseDate{}
+	}
+	return releaseDate{
+		date:      sql.NullString{String: t.Format(time.DateOnly), Valid: true},
+		precision: sql.NullString{String: string(p), Valid: true},
+	}
+}
+
+func (rd releaseDate) String() string {
+	if !rd.date.Valid {
+		return ""
+	}
+	t, err := time.Parse(time.DateOnly, rd.date.String)
+	if err != nil {
+		return ""
+	}
+	return releasedate.Format(t, releasedate.Precision(rd.precision.String))
+}
+
+// periodArgs returns the bounds of the period covered by the textual date,
+// or NULLs if the value is empty.
+func periodArgs(value string) (from any, to any) {
+	if value == "" {
+		return nil, nil
+	}
+	f, t, err := releasedate.ParsePeriod(value)
+	if err != nil {
+		return nil, nil
+	}
+	return f.Format(time.DateOnly), t.Format(time.DateOnly)
+}
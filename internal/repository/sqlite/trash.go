@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+)
+
+func (sr *SongsRepository) GetTrash(ctx context.Context, filters model.TrashFilters) ([]*model.DeletedSongOut, error) {
+	query := `
+	SELECT s.song_id, a.name, s.song, s.release_date, s.release_precision, s.deleted_at
+	FROM songs s
+	JOIN artists a ON a.artist_id = s.artist_id
+	WHERE s.deleted_at IS NOT NULL
+	ORDER BY s.deleted_at DESC, s.song_id ASC
+	LIMIT ?1 OFFSET ?2`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	args := []any{
+		filters.PageSize,
+		(filters.Page - 1) * filters.PageSize,
+	}
+
+	rows, err := sr.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	songs := []*model.DeletedSongOut{}
+
+	for rows.Next() {
+		var song model.DeletedSongOut
+		var rd releaseDate
+		err := rows.Scan(
+			&song.ID,
+			&song.Group,
+			&song.Song,
+			&rd.date,
+			&rd.precision,
+			&song.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		song.ReleaseDate = rd.String()
+
+		songs = append(songs, &song)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return songs, nil
+}
+
+// Restore takes the song out of the trash. DuplicateSongError is returned
+// if the group has got another song with the same title meanwhile.
+func (sr *SongsRepository) Restore(ctx context.Context, id uint64) error {
+	query := `
+	UPDATE songs
+	SET deleted_at = NULL
+	WHERE song_id = ?1 AND deleted_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	result, err := sr.db.ExecContext(ctx, query, id)
+	if err != nil {
+		var group, song string
+		lookupErr := sr.db.QueryRowContext(ctx, `
+		SELECT a.name, s.song
+		FROM songs s
+		JOIN artists a ON a.artist_id = s.artist_id
+		WHERE s.song_id = ?1`, id).Scan(&group, &song)
+		if lookupErr != nil {
+			return err
+		}
+		return sr.duplicateSong(ctx, err, group, song)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return db.ErrRecordNotFound
+	}
+	return nil
+}
+
+// purgedSongs selects the songs moved to the trash before ?1.
+const purgedSongs = `SELECT song_id FROM songs WHERE deleted_at IS NOT NULL AND deleted_at < ?1`
+
+// Purge permanently deletes the songs moved to the trash before the given time
+// and returns the number of deleted songs. The tracks of the albums they were
+// on are renumbered.
+func (sr *SongsRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	tx, err := sr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	renumberQuery := `
+	UPDATE album_tracks AS t
+	SET position = r.position
+	FROM (
+		SELECT album_id, song_id, ROW_NUMBER() OVER (PARTITION BY album_id ORDER BY position) AS position
+		FROM album_tracks
+		WHERE song_id NOT IN (` + purgedSongs + `)
+		AND album_id IN (SELECT album_id FROM album_tracks WHERE song_id IN (` + purgedSongs + `))
+	) r
+	WHERE t.album_id = r.album_id AND t.song_id = r.song_id AND t.position <> r.position`
+
+	_, err = tx.ExecContext(ctx, renumberQuery, timestamp(before))
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM songs WHERE song_id IN (`+purgedSongs+`)`, timestamp(before))
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"effective-mobile-song-library/internal/model"
+)
+
+// songTextQuery selects the verses of the song s as a JSON array.
+const songTextQuery = `(SELECT json_group_array(v.text ORDER BY v.position) FROM song_verses v WHERE v.song_id = s.song_id)`
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// GetVerses returns the verses of the song with their types, ordered by position.
+func (sr *SongsRepository) GetVerses(ctx context.Context, id uint64) ([]model.Verse, error) {
+	query := `
+	SELECT v.position, v.type, v.label, v.text
+	FROM song_verses v
+	JOIN songs s ON s.song_id = v.song_id
+	WHERE v.song_id = ?1 AND s.deleted_at IS NULL
+	ORDER BY v.position ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, sr.timeout)
+	defer cancel()
+
+	return queryVerses(ctx, sr.db, query, id)
+}
+
+func queryVerses(ctx context.Context, q queryer, query string, args ...any) ([]model.Verse, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verses := []model.Verse{}
+
+	for rows.Next() {
+		var verse model.Verse
+		err := rows.Scan(
+			&verse.Position,
+			&verse.Type,
+			&verse.Label,
+			&verse.Text,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		verses = append(verses, verse)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return verses, nil
+}
+
+// setVerses replaces the verses of the song with its text, unless the song
+// carries its verses. Verses keep their types and labels as long as their
+// text is the same.
+func setVerses(ctx context.Context, tx *sql.Tx, song *model.SongInfo) error {
+	previous, err := queryVerses(ctx, tx, `
+	SELECT position, type, label, text
+	FROM song_verses
+	WHERE song_id = ?1
+	ORDER BY position ASC`, song.ID)
+	if err != nil {
+		return err
+	}
+
+	verses := song.Verses
+	if verses == nil {
+		verses = model.VersesFromText(song.Text, previous)
+	}
+	if slices.Equal(verses, previous) {
+		return nil
+	}
+	return writeVerses(ctx, tx, song.ID, verses)
+}
+
+// writeVerses replaces the verses of the song.
+func writeVerses(ctx context.Context, tx *sql.Tx, songID uint64, verses []model.Verse) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM song_verses WHERE song_id = ?1`, songID)
+	if err != nil {
+		return err
+	}
+
+	for _, verse := range verses {
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO song_verses (song_id, position, type, label, text)
+		VALUES (?1, ?2, ?3, ?4, ?5)`, songID, verse.Position, verse.Type, verse.Label, verse.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// Package textsearch matches songs against search queries in Go, for the
+// storages that have no full-text search of their own. It understands the
+// same query syntax as the Postgres repository ("quoted phrases", or,
+// -exclusions) but matches whole words only, without stemming.
+package textsearch
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search query: alternatives separated by "or",
+// each of them a list of terms that must all match.
+type Query [][]term
+
+// term is a word or a quoted phrase of a search query.
+type term struct {
+	words  []string
+	negate bool
+}
+
+// Match is a song matching the query.
+type Match struct {
+	Rank    float64
+	Snippet string
+	Verses  []uint
+}
+
+// Parse parses the search query.
+func Parse(query string) Query {
+	var alternatives Query
+	var terms []term
+
+	for len(query) > 0 {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			break
+		}
+
+		negate := strings.HasPrefix(query, "-")
+		if negate {
+			query = query[1:]
+		}
+
+		var raw string
+		if strings.HasPrefix(query, `"`) {
+			end := strings.Index(query[1:], `"`)
+			if end < 0 {
+				raw, query = query[1:], ""
+			} else {
+				raw, query = query[1:end+1], query[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(query, unicode.IsSpace)
+			if end < 0 {
+				end = len(query)
+			}
+			raw, query = query[:end], query[end:]
+		}
+
+		if !negate && strings.EqualFold(raw, "or") {
+			if len(terms) > 0 {
+				alternatives = append(alternatives, terms)
+				terms = nil
+			}
+			continue
+		}
+
+		if w := words(raw); len(w) > 0 {
+			terms = append(terms, term{words: w, negate: negate})
+		}
+	}
+
+	if len(terms) > 0 {
+		alternatives = append(alternatives, terms)
+	}
+	return alternatives
+}
+
+// Match matches the song title and verses against the query. The snippet
+// highlights the searched words in up to three verses, and the verses that
+// match the query on their own are listed by their 1-based numbers.
+func (q Query) Match(title string, verses []string) (*Match, bool) {
+	doc := words(title + "\n" + strings.Join(verses, "\n"))
+	if !q.matches(doc) {
+		return nil, false
+	}
+
+	m := &Match{Rank: q.rank(doc), Verses: []uint{}}
+
+	highlight := q.positiveWords()
+	var fragments []string
+	for i, verse := range verses {
+		verseWords := words(verse)
+		if q.matches(verseWords) {
+			m.Verses = append(m.Verses, uint(i+1))
+		}
+		if len(fragments) < 3 && slices.ContainsFunc(verseWords, func(w string) bool { return highlight[w] }) {
+			fragments = append(fragments, markWords(verse, highlight))
+		}
+	}
+	m.Snippet = strings.Join(fragments, " ... ")
+
+	return m, true
+}
+
+// words splits the text into lowercase words of letters and digits.
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func (q Query) matches(doc []string) bool {
+	for _, terms := range q {
+		positive := false
+		matched := true
+		for _, t := range terms {
+			if !t.negate {
+				positive = true
+			}
+			if (countTerm(doc, t) > 0) == t.negate {
+				matched = false
+				break
+			}
+		}
+		if matched && positive {
+			return true
+		}
+	}
+	return false
+}
+
+// countTerm counts the occurrences of the word or phrase in the document.
+func countTerm(doc []string, t term) int {
+	count := 0
+	for i := 0; i+len(t.words) <= len(doc); i++ {
+		if slices.Equal(doc[i:i+len(t.words)], t.words) {
+			count++
+		}
+	}
+	return count
+}
+
+// rank grows with the number of occurrences of the searched terms
+// and falls with the length of the document.
+func (q Query) rank(doc []string) float64 {
+	hits := 0
+	for _, terms := range q {
+		for _, t := range terms {
+			if !t.negate {
+				hits += countTerm(doc, t)
+			}
+		}
+	}
+	return float64(hits) / float64(len(doc)+1)
+}
+
+func (q Query) positiveWords() map[string]bool {
+	set := make(map[string]bool)
+	for _, terms := range q {
+		for _, t := range terms {
+			if t.negate {
+				continue
+			}
+			for _, w := range t.words {
+				set[w] = true
+			}
+		}
+	}
+	return set
+}
+
+// markWords wraps the highlighted words of the text in <mark> tags,
+// the rest of the text is escaped as HTML.
+func markWords(text string, highlight map[string]bool) string {
+	var b strings.Builder
+	var word []rune
+
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		if highlight[strings.ToLower(string(word))] {
+			b.WriteString("<mark>" + string(word) + "</mark>")
+		} else {
+			b.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	flush()
+
+	return b.String()
+}
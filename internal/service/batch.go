@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/pkg/logger"
+)
+
+// InsertBatch adds the songs of the batch like Insert, looking up to
+// BatchConcurrency of them in the external API at a time. Every song gets
+// its own result in the order of the input, a failed song does not stop the
+// others.
+func (sl *SongLibraryService) InsertBatch(ctx context.Context, input model.SongsInput, editor string, onConflict string) []model.SongBatchResult {
+	results := make([]model.SongBatchResult, len(input.Groups))
+
+	sem := make(chan struct{}, max(sl.config.BatchConcurrency, 1))
+	var wg sync.WaitGroup
+
+	for i := range results {
+		results[i] = model.SongBatchResult{Group: input.Groups[i], Song: input.Songs[i]}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(result *model.SongBatchResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sl.insertResult(ctx, result, editor, onConflict)
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// insertResult adds the song of the result and records the outcome.
+// Unexpected errors are logged and only reported as a failure.
+func (sl *SongLibraryService) insertResult(ctx context.Context, result *model.SongBatchResult, editor string, onConflict string) {
+	song, status, err := sl.insert(ctx, result.Group, result.Song, editor, onConflict)
+
+	var duplicate *db.DuplicateSongError
+	switch {
+	case errors.As(err, &duplicate):
+		result.Status, result.ID = model.BatchStatusDuplicate, duplicate.ID
+	case errors.Is(err, external.ErrNoResponse):
+		result.Status, result.Error = model.BatchStatusFailed, err.Error()
+	case err != nil && ctx.Err() != nil:
+		result.Status, result.Error = model.BatchStatusFailed, "the operation was canceled"
+	case db.IsTimeout(err):
+		result.Status, result.Error = model.BatchStatusFailed, "the operation timed out"
+	case err != nil:
+		logger.PrintError(err, map[string]any{
+			"group": result.Group,
+			"song":  result.Song,
+		})
+		result.Status, result.Error = model.BatchStatusFailed, "the server encountered a problem and could not add the song"
+	default:
+		result.Status = status
+		if song != nil {
+			result.ID = song.ID
+		}
+	}
+}
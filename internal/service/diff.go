@@ -0,0 +1,109 @@
+package service
+
+import "effective-mobile-song-library/internal/model"
+
+const (
+	diffOpEqual  = "equal"
+	diffOpInsert = "insert"
+	diffOpDelete = "delete"
+)
+
+func diffRevisions(from *model.SongRevision, to *model.SongRevision) *model.SongDiff {
+	diff := &model.SongDiff{
+		From:   from.Revision,
+		To:     to.Revision,
+		Fields: []model.FieldChange{},
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"group", from.Group, to.Group},
+		{"song", from.Song, to.Song},
+		{"releaseDate", from.ReleaseDate, to.ReleaseDate},
+		{"link", from.Link, to.Link},
+		{"language", from.Language, to.Language},
+	}
+	for _, f := range fields {
+		if f.from != f.to {
+			diff.Fields = append(diff.Fields, model.FieldChange{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+
+	diff.Verses = diffVerses(from.Text, to.Text)
+
+	return diff
+}
+
+// maxDiffCells caps the size of the LCS table built by diffVerses,
+// a table of int32 taking 4 bytes per cell.
+const maxDiffCells = 1 << 20
+
+// diffVerses builds a verse-level diff using the longest common subsequence
+// of the two texts, so that moved or edited verses show up as a deletion
+// followed by an insertion. The verses the texts start and end with are
+// matched first, and only the changed middle goes into the LCS table; if it
+// would exceed maxDiffCells the middle is reported as deleted and inserted
+// as a whole.
+func diffVerses(a []string, b []string) []model.VerseChange {
+	changes := make([]model.VerseChange, 0, max(len(a), len(b)))
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		changes = append(changes, model.VerseChange{Op: diffOpEqual, FromVerse: uint(prefix + 1), ToVerse: uint(prefix + 1), Text: a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	// the changed middle
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+
+	var lcs []int32
+	if n > 0 && m > 0 && (n+1)*(m+1) <= maxDiffCells {
+		// lcs[i*(m+1)+j] is the length of the LCS of midA[i:] and midB[j:]
+		lcs = make([]int32, (n+1)*(m+1))
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+				} else {
+					lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+				}
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for lcs != nil && i < n && j < m {
+		switch {
+		case midA[i] == midB[j]:
+			changes = append(changes, model.VerseChange{Op: diffOpEqual, FromVerse: uint(prefix + i + 1), ToVerse: uint(prefix + j + 1), Text: midA[i]})
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			changes = append(changes, model.VerseChange{Op: diffOpDelete, FromVerse: uint(prefix + i + 1), Text: midA[i]})
+			i++
+		default:
+			changes = append(changes, model.VerseChange{Op: diffOpInsert, ToVerse: uint(prefix + j + 1), Text: midB[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		changes = append(changes, model.VerseChange{Op: diffOpDelete, FromVerse: uint(prefix + i + 1), Text: midA[i]})
+	}
+	for ; j < m; j++ {
+		changes = append(changes, model.VerseChange{Op: diffOpInsert, ToVerse: uint(prefix + j + 1), Text: midB[j]})
+	}
+
+	for k := 0; k < suffix; k++ {
+		fi, ti := len(a)-suffix+k, len(b)-suffix+k
+		changes = append(changes, model.VerseChange{Op: diffOpEqual, FromVerse: uint(fi + 1), ToVerse: uint(ti + 1), Text: a[fi]})
+	}
+
+	return changes
+}
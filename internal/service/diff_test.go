@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+
+	"effective-mobile-song-library/internal/model"
+)
+
+// formatChanges renders the changes as "=from:to text", "-from text"
+// and "+to text".
+func formatChanges(changes []model.VerseChange) []string {
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		switch c.Op {
+		case diffOpEqual:
+			out = append(out, fmt.Sprintf("=%d:%d %s", c.FromVerse, c.ToVerse, c.Text))
+		case diffOpDelete:
+			out = append(out, fmt.Sprintf("-%d %s", c.FromVerse, c.Text))
+		case diffOpInsert:
+			out = append(out, fmt.Sprintf("+%d %s", c.ToVerse, c.Text))
+		}
+	}
+	return out
+}
+
+func TestDiffVerses(t *testing.T) {
+	tests := []struct {
+		name string
+		from []string
+		to   []string
+		want []string
+	}{
+		{
+			name: "same lyrics",
+			from: []string{"a", "b"},
+			to:   []string{"a", "b"},
+			want: []string{"=1:1 a", "=2:2 b"},
+		},
+		{
+			name: "empty revision",
+			from: nil,
+			to:   []string{"a", "b"},
+			want: []string{"+1 a", "+2 b"},
+		},
+		{
+			name: "verse inserted in the middle",
+			from: []string{"a", "c"},
+			to:   []string{"a", "b", "c"},
+			want: []string{"=1:1 a", "+2 b", "=2:3 c"},
+		},
+		{
+			name: "verse edited",
+			from: []string{"a", "b", "c"},
+			to:   []string{"a", "B", "c"},
+			want: []string{"=1:1 a", "-2 b", "+2 B", "=3:3 c"},
+		},
+		{
+			name: "verse moved",
+			from: []string{"a", "b", "c", "d"},
+			to:   []string{"a", "c", "d", "b"},
+			want: []string{"=1:1 a", "-2 b", "=3:2 c", "=4:3 d", "+4 b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatChanges(diffVerses(tt.from, tt.to))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffVersesSizeCap(t *testing.T) {
+	// changed middles of n verses, the LCS table takes (n+1)*(n+1) cells
+	tests := []struct {
+		name    string
+		verses  int
+		matched bool
+	}{
+		{name: "under the cap", verses: 1000, matched: true},
+		{name: "over the cap", verses: 1100, matched: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the texts share the verse "common" in their changed middles
+			from := []string{"first"}
+			to := []string{"first"}
+			for i := range tt.verses {
+				from = append(from, "from "+strconv.Itoa(i))
+				to = append(to, "to "+strconv.Itoa(i))
+			}
+			from[tt.verses/2] = "common"
+			to[tt.verses/2] = "common"
+			from = append(from, "last")
+			to = append(to, "last")
+
+			changes := diffVerses(from, to)
+
+			var equal, deleted, inserted int
+			for _, c := range changes {
+				switch c.Op {
+				case diffOpEqual:
+					equal++
+				case diffOpDelete:
+					deleted++
+				case diffOpInsert:
+					inserted++
+				}
+			}
+
+			wantEqual, wantChanged := 2, tt.verses
+			if tt.matched {
+				wantEqual, wantChanged = 3, tt.verses-1
+			}
+			if equal != wantEqual || deleted != wantChanged || inserted != wantChanged {
+				t.Errorf("got %d equal, %d deleted and %d inserted verses, want %d equal and %d deleted and inserted",
+					equal, deleted, inserted, wantEqual, wantChanged)
+			}
+			if first, last := changes[0], changes[len(changes)-1]; first.Op != diffOpEqual || last.Op != diffOpEqual {
+				t.Errorf("the first and last verses are not matched: %v, %v", first, last)
+			}
+		})
+	}
+}
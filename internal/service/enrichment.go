@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"effective-mobile-song-library/config"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/pkg/logger"
+)
+
+// mergeField is a field of the song details the providers may supply.
+// Its size is zero if the provider has not supplied it.
+type mergeField struct {
+	key   string
+	size  func(song *model.SongInfo) int
+	set   func(to *model.SongInfo, from *model.SongInfo)
+	equal func(a *model.SongInfo, b *model.SongInfo) bool
+}
+
+var mergeFields = []mergeField{
+	{
+		key:  model.FieldReleaseDate,
+		size: func(song *model.SongInfo) int { return len(song.ReleaseDate) },
+		set:  func(to *model.SongInfo, from *model.SongInfo) { to.ReleaseDate = from.ReleaseDate },
+		equal: func(a *model.SongInfo, b *model.SongInfo) bool {
+			return a.ReleaseDate == b.ReleaseDate
+		},
+	},
+	{
+		key:  model.FieldLink,
+		size: func(song *model.SongInfo) int { return len(song.Link) },
+		set:  func(to *model.SongInfo, from *model.SongInfo) { to.Link = from.Link },
+		equal: func(a *model.SongInfo, b *model.SongInfo) bool {
+			return a.Link == b.Link
+		},
+	},
+	{
+		key:  model.FieldText,
+		size: func(song *model.SongInfo) int { return len(song.Text) },
+		set:  func(to *model.SongInfo, from *model.SongInfo) { to.Text = from.Text },
+		equal: func(a *model.SongInfo, b *model.SongInfo) bool {
+			return slices.Equal(a.Text, b.Text)
+		},
+	},
+}
+
+// mergeDetails returns the song with the fields the details supply, along
+// with the keys of the ones that have changed. The fields edited by hand are
+// only replaced with manual ones, the others are kept if the details do not
+// supply them. The language is the one given with manual lyrics, or follows
+// the lyrics of the providers if they are taken.
+func mergeDetails(song *model.SongInfo, details *model.SongInfo) (model.SongInfo, []string) {
+	updated := *song
+	updated.Sources = maps.Clone(song.Sources)
+
+	var changed []string
+	for _, field := range mergeFields {
+		source, ok := details.Sources[field.key]
+		if !ok || source != model.SourceManual && song.Sources[field.key] == model.SourceManual {
+			continue
+		}
+		if updated.Sources == nil {
+			updated.Sources = make(map[string]string)
+		}
+		updated.Sources[field.key] = source
+		if field.equal(&updated, details) {
+			continue
+		}
+		field.set(&updated, details)
+		changed = append(changed, field.key)
+	}
+
+	switch {
+	case details.Sources[model.FieldText] == model.SourceManual:
+		updated.Language = details.Language
+	case slices.Contains(changed, model.FieldText):
+		updated.Language = detectLanguage(updated.Text)
+	}
+	return updated, changed
+}
+
+// providerAnswer is the details of the song supplied by a provider.
+type providerAnswer struct {
+	provider string
+	song     *model.SongInfo
+}
+
+// enrich asks the providers for the details of the song in their order and
+// merges the answers field by field according to the merge rules, recording
+// the provider that supplied each field in Sources. The next provider is only
+// asked while the answers so far do not settle every field, so a failing or
+// unknowing provider is backed by the following ones.
+//
+// If no provider knows the song external.ErrUnknownSong is returned, if some
+// could not answer the first of their errors is.
+func (sl *SongLibraryService) enrich(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	var answers []providerAnswer
+	asked := make(map[string]bool, len(sl.providers))
+	var firstErr error
+
+	for _, p := range sl.providers {
+		if len(answers) > 0 && sl.settled(answers, asked) {
+			break
+		}
+
+		asked[p.Name()] = true
+		songInfo, err := p.GetSongInfoWithDetails(ctx, group, song)
+		switch {
+		case err == nil:
+			answers = append(answers, providerAnswer{provider: p.Name(), song: songInfo})
+			continue
+		case ctx.Err() != nil:
+			return nil, err
+		case firstErr == nil && !errors.Is(err, external.ErrBadRequest):
+			firstErr = err
+		}
+
+		logger.PrintDebug("provider did not supply song info", map[string]any{
+			"provider": p.Name(),
+			"group":    group,
+			"song":     song,
+			"error":    err.Error(),
+		})
+	}
+
+	if len(answers) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, external.ErrUnknownSong
+	}
+
+	first := answers[0].song
+	merged := &model.SongInfo{Group: first.Group, Song: first.Song, Sources: make(map[string]string)}
+	for _, field := range mergeFields {
+		answer, ok := pickAnswer(answers, field, sl.mergeRule(field.key))
+		if ok {
+			field.set(merged, answer.song)
+			merged.Sources[field.key] = answer.provider
+		}
+	}
+	return merged, nil
+}
+
+// settled reports whether asking more providers cannot change the merge of
+// any field: under every rule the winning answer is already known.
+func (sl *SongLibraryService) settled(answers []providerAnswer, asked map[string]bool) bool {
+	for _, field := range mergeFields {
+		rule := sl.mergeRule(field.key)
+		if rule == config.MergeLongest {
+			return false
+		}
+
+		for _, name := range priorityList(rule) {
+			if !asked[name] {
+				return false
+			}
+			if answer, ok := findAnswer(answers, name); ok && field.size(answer.song) > 0 {
+				break
+			}
+		}
+
+		if _, ok := pickAnswer(answers, field, config.MergeFirst); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pickAnswer returns the answer the field is taken from under the rule, ok is
+// false if no provider has supplied the field. Under a priority list the
+// providers not listed only supply the field if none of the listed do.
+func pickAnswer(answers []providerAnswer, field mergeField, rule string) (providerAnswer, bool) {
+	for _, name := range priorityList(rule) {
+		if answer, ok := findAnswer(answers, name); ok && field.size(answer.song) > 0 {
+			return answer, true
+		}
+	}
+
+	var picked providerAnswer
+	var pickedSize int
+	for _, answer := range answers {
+		size := field.size(answer.song)
+		if size > pickedSize {
+			picked, pickedSize = answer, size
+			if rule != config.MergeLongest {
+				break
+			}
+		}
+	}
+	return picked, pickedSize > 0
+}
+
+func findAnswer(answers []providerAnswer, provider string) (providerAnswer, bool) {
+	for _, answer := range answers {
+		if answer.provider == provider {
+			return answer, true
+		}
+	}
+	return providerAnswer{}, false
+}
+
+// mergeRule returns the configured merge rule of the field.
+func (sl *SongLibraryService) mergeRule(field string) string {
+	switch field {
+	case model.FieldReleaseDate:
+		return sl.config.MergeReleaseDate
+	case model.FieldLink:
+		return sl.config.MergeLink
+	case model.FieldText:
+		return sl.config.MergeText
+	}
+	return config.MergeFirst
+}
+
+// priorityList returns the provider names listed by the rule,
+// or nil if it is not a priority list.
+func priorityList(rule string) []string {
+	if rule == config.MergeFirst || rule == config.MergeLongest {
+		return nil
+	}
+
+	names := strings.Split(rule, ",")
+	for i, name := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return names
+}
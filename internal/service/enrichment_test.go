@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"effective-mobile-song-library/config"
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/pkg/breaker"
+)
+
+// stubProvider answers with the song, or with ErrBadRequest if it is nil.
+type stubProvider struct {
+	name  string
+	song  *model.SongInfo
+	asked *[]string
+}
+
+func (p stubProvider) Name() string {
+	return p.name
+}
+
+func (p stubProvider) GetSongInfoWithDetails(ctx context.Context, group string, song string) (*model.SongInfo, error) {
+	*p.asked = append(*p.asked, p.name)
+	if p.song == nil {
+		return nil, external.ErrBadRequest
+	}
+	return p.song, nil
+}
+
+func (p stubProvider) BreakerState() breaker.State {
+	return breaker.State{State: breaker.StateClosed}
+}
+
+func TestEnrichMergeRules(t *testing.T) {
+	short := &model.SongInfo{ReleaseDate: "2006", Link: "https://a", Text: []string{"one"}}
+	long := &model.SongInfo{ReleaseDate: "16.07.2006", Text: []string{"one", "two"}}
+	longest := &model.SongInfo{Link: "https://c.example", Text: []string{"one", "two", "three"}}
+
+	tests := []struct {
+		name        string
+		answers     map[string]*model.SongInfo
+		releaseDate string
+		link        string
+		text        string
+		wantSources map[string]string
+		wantAsked   int
+	}{
+		{
+			name:        "first answer of every field",
+			answers:     map[string]*model.SongInfo{"a": short, "b": long, "c": longest},
+			releaseDate: config.MergeFirst,
+			link:        config.MergeFirst,
+			text:        config.MergeFirst,
+			wantSources: map[string]string{model.FieldReleaseDate: "a", model.FieldLink: "a", model.FieldText: "a"},
+			wantAsked:   1,
+		},
+		{
+			name:        "first provider supplying the field",
+			answers:     map[string]*model.SongInfo{"a": nil, "b": long, "c": longest},
+			releaseDate: config.MergeFirst,
+			link:        config.MergeFirst,
+			text:        config.MergeFirst,
+			wantSources: map[string]string{model.FieldReleaseDate: "b", model.FieldLink: "c", model.FieldText: "b"},
+			wantAsked:   3,
+		},
+		{
+			name:        "longest answer",
+			answers:     map[string]*model.SongInfo{"a": short, "b": long, "c": longest},
+			releaseDate: config.MergeLongest,
+			link:        config.MergeFirst,
+			text:        config.MergeLongest,
+			wantSources: map[string]string{model.FieldReleaseDate: "b", model.FieldLink: "a", model.FieldText: "c"},
+			wantAsked:   3,
+		},
+		{
+			name:        "priority list",
+			answers:     map[string]*model.SongInfo{"a": short, "b": long, "c": longest},
+			releaseDate: config.MergeFirst,
+			link:        "c, a",
+			text:        "b,c",
+			wantSources: map[string]string{model.FieldReleaseDate: "a", model.FieldLink: "c", model.FieldText: "b"},
+			wantAsked:   3,
+		},
+		{
+			name:        "priority list without the field",
+			answers:     map[string]*model.SongInfo{"a": short, "b": long, "c": longest},
+			releaseDate: "c",
+			link:        "b",
+			text:        "a",
+			wantSources: map[string]string{model.FieldReleaseDate: "a", model.FieldLink: "a", model.FieldText: "a"},
+			wantAsked:   3,
+		},
+		{
+			name:        "priority list settled before the last provider",
+			answers:     map[string]*model.SongInfo{"a": short, "b": long, "c": longest},
+			releaseDate: "b",
+			link:        config.MergeFirst,
+			text:        "a,b",
+			wantSources: map[string]string{model.FieldReleaseDate: "b", model.FieldLink: "a", model.FieldText: "a"},
+			wantAsked:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked []string
+			var providers []Provider
+			for _, name := range []string{"a", "b", "c"} {
+				providers = append(providers, stubProvider{name: name, song: tt.answers[name], asked: &asked})
+			}
+			sl := NewSongLibraryService(nil, providers, &config.Config{
+				MergeReleaseDate: tt.releaseDate,
+				MergeLink:        tt.link,
+				MergeText:        tt.text,
+			})
+
+			song, err := sl.enrich(context.Background(), "Muse", "Supermassive Black Hole")
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if !maps.Equal(song.Sources, tt.wantSources) {
+				t.Errorf("got sources %v, want %v", song.Sources, tt.wantSources)
+			}
+			for _, field := range mergeFields {
+				from := tt.answers[tt.wantSources[field.key]]
+				if !field.equal(song, from) {
+					t.Errorf("%s is not the one of %s", field.key, tt.wantSources[field.key])
+				}
+			}
+			if len(asked) != tt.wantAsked {
+				t.Errorf("got providers %v asked, want %d of them", asked, tt.wantAsked)
+			}
+		})
+	}
+}
+
+func TestEnrichUnknownSong(t *testing.T) {
+	var asked []string
+	sl := NewSongLibraryService(nil, []Provider{
+		stubProvider{name: "a", asked: &asked},
+		stubProvider{name: "b", asked: &asked},
+	}, &config.Config{MergeReleaseDate: config.MergeFirst, MergeLink: config.MergeFirst, MergeText: config.MergeFirst})
+
+	_, err := sl.enrich(context.Background(), "Muse", "Supermassive Black Hole")
+	if !errors.Is(err, external.ErrUnknownSong) {
+		t.Errorf("got error %v, want %v", err, external.ErrUnknownSong)
+	}
+	if len(asked) != 2 {
+		t.Errorf("got providers %v asked, want both", asked)
+	}
+}
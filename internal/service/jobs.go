@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/pkg/logger"
+)
+
+// jobPool runs the asynchronous jobs. The storage serves as the queue:
+// the workers claim the pending jobs from it, so that the jobs left over
+// by a restart are run after the next start.
+type jobPool struct {
+	wake      chan struct{}
+	draining  chan struct{}
+	drainOnce sync.Once
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func newJobPool() *jobPool {
+	return &jobPool{
+		wake:     make(chan struct{}, 1),
+		draining: make(chan struct{}),
+		cancel:   func() {},
+	}
+}
+
+// notify wakes up an idle worker to look for pending jobs.
+func (p *jobPool) notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// EnqueueInsert saves a job adding the song like Insert and returns it
+// without waiting, the job is run by the workers started with StartJobs.
+func (sl *SongLibraryService) EnqueueInsert(ctx context.Context, group string, song string, editor string, onConflict string) (*model.Job, error) {
+	job := &model.Job{
+		Group:      group,
+		Song:       song,
+		Editor:     editor,
+		OnConflict: onConflict,
+	}
+
+	err := sl.songRepo.InsertJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	sl.jobs.notify()
+	return job, nil
+}
+
+// GetJob returns the job along with the song it has added or updated,
+// unless the song has been deleted since.
+func (sl *SongLibraryService) GetJob(ctx context.Context, id uint64) (*model.JobOut, error) {
+	job, err := sl.songRepo.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &model.JobOut{Job: job}
+	if job.SongID != 0 {
+		out.SongInfo, err = sl.songRepo.Get(ctx, job.SongID)
+		if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// StartJobs makes the jobs whose lease is over pending again and starts the
+// workers running the pending jobs. They run until ctx is done or the jobs
+// are drained, the abandoned jobs being requeued every JobLease meanwhile.
+func (sl *SongLibraryService) StartJobs(ctx context.Context, workers int) error {
+	err := sl.requeueJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, sl.jobs.cancel = context.WithCancel(ctx)
+	for range max(workers, 1) {
+		sl.jobs.wg.Add(1)
+		go func() {
+			defer sl.jobs.wg.Done()
+			sl.runJobs(ctx)
+		}()
+	}
+
+	sl.jobs.wg.Add(1)
+	go func() {
+		defer sl.jobs.wg.Done()
+		ticker := time.NewTicker(sl.config.JobLease)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := sl.requeueJobs(ctx)
+				if err != nil && ctx.Err() == nil {
+					logger.PrintError(err, nil)
+				}
+			case <-sl.jobs.draining:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// requeueJobs makes the jobs whose lease has not been renewed for JobLease
+// pending again, they have been abandoned by a crashed or stopped instance.
+func (sl *SongLibraryService) requeueJobs(ctx context.Context) error {
+	requeued, err := sl.songRepo.RequeueJobs(ctx, time.Now().Add(-sl.config.JobLease))
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		logger.PrintInfo("requeued abandoned jobs", map[string]any{
+			"jobs": requeued,
+		})
+		sl.jobs.notify()
+	}
+	return nil
+}
+
+// DrainJobs lets the workers run the pending jobs and stops them once there
+// are none left. If ctx is done first, the running jobs are abandoned and
+// run again once their lease is over.
+func (sl *SongLibraryService) DrainJobs(ctx context.Context) error {
+	sl.jobs.drainOnce.Do(func() {
+		close(sl.jobs.draining)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		sl.jobs.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		sl.jobs.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// runJobs runs the pending jobs one at a time, waiting for new ones when
+// there are none left.
+func (sl *SongLibraryService) runJobs(ctx context.Context) {
+	for {
+		job, err := sl.songRepo.ClaimJob(ctx)
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			select {
+			case <-sl.jobs.wake:
+			case <-sl.jobs.draining:
+				return
+			case <-ctx.Done():
+				return
+			}
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			logger.PrintError(err, nil)
+
+			// the storage is unavailable, give it some time
+			select {
+			case <-time.After(time.Second):
+			case <-sl.jobs.draining:
+				return
+			case <-ctx.Done():
+				return
+			}
+		default:
+			// another worker may take the next pending job meanwhile
+			sl.jobs.notify()
+			sl.runJob(ctx, job)
+		}
+	}
+}
+
+// runJob adds the song of the job and saves the outcome, renewing its lease
+// meanwhile. A job stopped halfway is left running, to be run again once its
+// lease is over, and a job requeued meanwhile is dropped.
+func (sl *SongLibraryService) runJob(ctx context.Context, job *model.Job) {
+	jobCtx, abandon := context.WithCancel(ctx)
+	defer abandon()
+	stopRenewing := sl.renewJob(jobCtx, abandon, job)
+
+	result := model.SongBatchResult{Group: job.Group, Song: job.Song}
+	sl.insertResult(jobCtx, &result, job.Editor, job.OnConflict)
+	stopRenewing()
+	if jobCtx.Err() != nil {
+		return
+	}
+
+	job.Status = model.JobStatusDone
+	if result.Status == model.BatchStatusFailed {
+		job.Status = model.JobStatusFailed
+	}
+	job.Result, job.SongID, job.Error = result.Status, result.ID, result.Error
+
+	err := sl.songRepo.FinishJob(ctx, job)
+	switch {
+	case errors.Is(err, db.ErrEditConflict):
+		logger.PrintInfo("dropped the outcome of a requeued job", map[string]any{
+			"job": job.ID,
+		})
+	case err != nil:
+		logger.PrintError(err, map[string]any{
+			"job": job.ID,
+		})
+	}
+}
+
+// renewJob renews the lease of the job every third of JobLease until the
+// returned function is called. The job is abandoned if it has been requeued.
+func (sl *SongLibraryService) renewJob(ctx context.Context, abandon context.CancelFunc, job *model.Job) func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(sl.config.JobLease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				err := sl.songRepo.RenewJob(ctx, job)
+				switch {
+				case errors.Is(err, db.ErrEditConflict):
+					logger.PrintInfo("abandoned a requeued job", map[string]any{
+						"job": job.ID,
+					})
+					abandon()
+					return
+				case err != nil && ctx.Err() == nil:
+					logger.PrintError(err, map[string]any{
+						"job": job.ID,
+					})
+				}
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/cache"
+	"effective-mobile-song-library/internal/repository/db"
+	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/pkg/logger"
+	"effective-mobile-song-library/pkg/validator"
+)
+
+// reenrichEditor is the editor recorded in the revisions made by the re-enrichment.
+const reenrichEditor = "re-enrichment"
+
+// maxEnrichmentRuns is the number of run reports kept.
+const maxEnrichmentRuns = 10
+
+// enrichmentRuns keeps the reports of the latest re-enrichment runs.
+type enrichmentRuns struct {
+	mu   sync.Mutex
+	runs []*model.EnrichmentRun
+}
+
+func (r *enrichmentRuns) add(run *model.EnrichmentRun) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs = append([]*model.EnrichmentRun{run}, r.runs...)
+	r.runs = r.runs[:min(len(r.runs), maxEnrichmentRuns)]
+}
+
+// EnrichmentRuns returns the reports of the latest re-enrichment runs,
+// the latest first.
+func (sl *SongLibraryService) EnrichmentRuns() []*model.EnrichmentRun {
+	sl.runs.mu.Lock()
+	defer sl.runs.mu.Unlock()
+
+	return append([]*model.EnrichmentRun{}, sl.runs.runs...)
+}
+
+// StartReenrichment looks up the stale songs again every ReenrichInterval
+// until ctx is done. Nothing is started if the interval is not set.
+func (sl *SongLibraryService) StartReenrichment(ctx context.Context) {
+	if sl.config.ReenrichInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(sl.config.ReenrichInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sl.Reenrich(ctx)
+			}
+		}
+	}()
+}
+
+// Reenrich fetches the details of up to ReenrichBatchSize stale songs from
+// the providers again: the songs enriched longer than ReenrichMaxAge ago, or
+// ReenrichMissingAge ago if they miss some details. The changed details are
+// saved as a new revision of the song, except for the ones edited by hand.
+// The report of the run is returned and kept for EnrichmentRuns.
+func (sl *SongLibraryService) Reenrich(ctx context.Context) *model.EnrichmentRun {
+	run := &model.EnrichmentRun{StartedAt: time.Now(), Songs: []model.EnrichmentResult{}}
+	defer sl.runs.add(run)
+
+	ids, err := sl.songRepo.GetStaleSongs(ctx,
+		run.StartedAt.Add(-sl.config.ReenrichMaxAge),
+		run.StartedAt.Add(-sl.config.ReenrichMissingAge),
+		run.StartedAt.Add(-sl.config.ReenrichRetryAge),
+		sl.config.ReenrichBatchSize,
+	)
+	if err != nil {
+		logger.PrintError(err, nil)
+		run.Error = "the stale songs could not be found"
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			run.Error = "the run was interrupted"
+			break
+		}
+
+		result := sl.reenrichSong(ctx, id)
+		switch result.Status {
+		case model.EnrichmentUpdated:
+			run.Updated++
+		case model.EnrichmentUnchanged:
+			run.Unchanged++
+		case model.EnrichmentSkipped:
+			run.Skipped++
+		case model.EnrichmentNotFound:
+			run.NotFound++
+		case model.EnrichmentFailed:
+			run.Failed++
+		}
+		run.Songs = append(run.Songs, result)
+	}
+	run.FinishedAt = time.Now()
+
+	logger.PrintInfo("re-enriched stale songs", map[string]any{
+		"updated":   run.Updated,
+		"unchanged": run.Unchanged,
+		"skipped":   run.Skipped,
+		"notFound":  run.NotFound,
+		"failed":    run.Failed,
+	})
+	return run
+}
+
+// reenrichSong fetches the details of the song again and saves the ones that
+// have changed, keeping the details edited by hand and the ones the providers
+// no longer supply. The song is marked as enriched unless it has failed.
+func (sl *SongLibraryService) reenrichSong(ctx context.Context, id uint64) model.EnrichmentResult {
+	result := model.EnrichmentResult{SongID: id}
+	now := time.Now()
+
+	song, err := sl.songRepo.Get(ctx, id)
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		// the song has been deleted since it was found stale
+		result.Status = model.EnrichmentSkipped
+		return result
+	case err != nil:
+		return sl.failEnrichment(ctx, result, err)
+	}
+	result.Group, result.Song = song.Group, song.Song
+
+	provided := slices.ContainsFunc(mergeFields, func(field mergeField) bool {
+		return song.Sources[field.key] != model.SourceManual
+	})
+	if !provided {
+		result.Status = model.EnrichmentSkipped
+		return sl.markEnriched(ctx, result, now)
+	}
+
+	details, err := sl.enrich(cache.Refresh(ctx), song.Group, song.Song)
+	switch {
+	case errors.Is(err, external.ErrBadRequest):
+		result.Status = model.EnrichmentNotFound
+		return sl.markEnriched(ctx, result, now)
+	case err != nil:
+		return sl.failEnrichment(ctx, result, err)
+	}
+
+	updated, changed := mergeDetails(song, details)
+	if len(changed) == 0 {
+		result.Status = model.EnrichmentUnchanged
+		return sl.markEnriched(ctx, result, now)
+	}
+	result.Fields = changed
+	updated.EnrichedAt = &now
+
+	// the details are saved like the ones edited through the API
+	v := validator.New()
+	if model.ValidateSongInfo(v, &updated); !v.Valid() {
+		return sl.failEnrichment(ctx, result, fmt.Errorf("invalid details from providers: %v", sortedErrors(v.Errors)))
+	}
+
+	err = sl.songRepo.Update(ctx, &updated, model.SongChange{Editor: reenrichEditor})
+	if err != nil {
+		return sl.failEnrichment(ctx, result, err)
+	}
+	result.Status = model.EnrichmentUpdated
+	return result
+}
+
+func (sl *SongLibraryService) markEnriched(ctx context.Context, result model.EnrichmentResult, at time.Time) model.EnrichmentResult {
+	err := sl.songRepo.SetEnrichedAt(ctx, result.SongID, at)
+	if err != nil {
+		return sl.failEnrichment(ctx, result, err)
+	}
+	return result
+}
+
+// failEnrichment reports the failure of the song, unexpected errors
+// are only logged. The song is left out of the runs for ReenrichRetryAge
+// unless the run was interrupted.
+func (sl *SongLibraryService) failEnrichment(ctx context.Context, result model.EnrichmentResult, err error) model.EnrichmentResult {
+	result.Status, result.Fields = model.EnrichmentFailed, nil
+
+	if ctx.Err() == nil {
+		err := sl.songRepo.SetEnrichmentFailedAt(ctx, result.SongID, time.Now())
+		if err != nil {
+			logger.PrintError(err, map[string]any{
+				"songId": result.SongID,
+			})
+		}
+	}
+
+	switch {
+	case errors.Is(err, external.ErrNoResponse):
+		result.Error = err.Error()
+	case errors.Is(err, db.ErrEditConflict):
+		result.Error = "the song has been edited during the run"
+	case ctx.Err() != nil:
+		result.Error = "the run was interrupted"
+	case db.IsTimeout(err):
+		result.Error = "the operation timed out"
+	default:
+		logger.PrintError(err, map[string]any{
+			"songId": result.SongID,
+		})
+		result.Error = "the server encountered a problem and could not re-enrich the song"
+	}
+	return result
+}
+
+// sortedErrors formats the validation errors in the order of their keys.
+func sortedErrors(errs map[string]string) string {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var s string
+	for i, key := range keys {
+		if i > 0 {
+			s += ", "
+		}
+		s += key + " " + errs[key]
+	}
+	return s
+}
@@ -1,78 +1,180 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"maps"
 	"reflect"
+	"slices"
+	"time"
+	"unicode"
 
+	"effective-mobile-song-library/config"
 	"effective-mobile-song-library/internal/model"
+	"effective-mobile-song-library/internal/repository/cache"
+	"effective-mobile-song-library/internal/repository/db"
 	"effective-mobile-song-library/internal/repository/external"
+	"effective-mobile-song-library/pkg/breaker"
 	"effective-mobile-song-library/pkg/logger"
 )
 
 type (
 	SongStorage interface {
-		Get(id uint64) (*model.SongInfo, error)
-		GetAll(filters model.SongFilters) ([]*model.SongInfo, error)
-		GetFullText(id uint64) (*string, error)
-		GetText(filters model.SongTextFilters) (*string, error)
-		Insert(*model.SongInfo) error
-		Update(songs *model.SongInfo) error
-		Delete(id uint64) error
+		Get(ctx context.Context, id uint64) (*model.SongInfo, error)
+		GetByTitle(ctx context.Context, group string, song string) (*model.SongInfo, error)
+		GetAll(ctx context.Context, filters model.SongFilters) (*model.Songs, error)
+		GetFullText(ctx context.Context, id uint64) (*string, error)
+		GetText(ctx context.Context, filters model.SongTextFilters) (*string, error)
+		GetVerses(ctx context.Context, id uint64) ([]model.Verse, error)
+		Search(ctx context.Context, filters model.SongSearchFilters) ([]*model.SongSearchResult, error)
+		Insert(ctx context.Context, song *model.SongInfo, change model.SongChange) error
+		Update(ctx context.Context, song *model.SongInfo, change model.SongChange) error
+		Delete(ctx context.Context, id uint64, version uint) error
+
+		GetArtist(ctx context.Context, id uint64) (*model.Artist, error)
+		GetArtists(ctx context.Context, filters model.ArtistFilters) ([]*model.Artist, error)
+
+		GetAlbum(ctx context.Context, id uint64) (*model.Album, error)
+		GetAlbums(ctx context.Context, filters model.AlbumFilters) ([]*model.AlbumOut, error)
+		InsertAlbum(ctx context.Context, album *model.Album) error
+		UpdateAlbum(ctx context.Context, album *model.Album) error
+		DeleteAlbum(ctx context.Context, id uint64) error
+		SetAlbumTrack(ctx context.Context, albumID uint64, track model.AlbumTrackInput) error
+		RemoveAlbumTrack(ctx context.Context, albumID uint64, songID uint64) error
+
+		GetPlaylist(ctx context.Context, id uint64) (*model.Playlist, error)
+		GetPlaylists(ctx context.Context, filters model.PlaylistFilters) ([]*model.PlaylistOut, error)
+		InsertPlaylist(ctx context.Context, playlist *model.Playlist) error
+		UpdatePlaylist(ctx context.Context, playlist *model.Playlist) error
+		DeletePlaylist(ctx context.Context, id uint64) error
+		AddPlaylistEntry(ctx context.Context, playlistID uint64, entry model.PlaylistEntryInput) error
+		MovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64, position uint) error
+		RemovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64) error
+
+		GetRevisions(ctx context.Context, filters model.SongRevisionFilters) ([]*model.SongRevisionOut, error)
+		GetRevision(ctx context.Context, songID uint64, revision uint) (*model.SongRevision, error)
+
+		GetTrash(ctx context.Context, filters model.TrashFilters) ([]*model.DeletedSongOut, error)
+		Restore(ctx context.Context, id uint64) error
+		Purge(ctx context.Context, before time.Time) (int64, error)
+
+		InsertJob(ctx context.Context, job *model.Job) error
+		GetJob(ctx context.Context, id uint64) (*model.Job, error)
+		ClaimJob(ctx context.Context) (*model.Job, error)
+		FinishJob(ctx context.Context, job *model.Job) error
+		RenewJob(ctx context.Context, job *model.Job) error
+		RequeueJobs(ctx context.Context, runningBefore time.Time) (int64, error)
+
+		GetStaleSongs(ctx context.Context, staleBefore time.Time, incompleteBefore time.Time, failedBefore time.Time, limit int) ([]uint64, error)
+		SetEnrichedAt(ctx context.Context, id uint64, at time.Time) error
+		SetEnrichmentFailedAt(ctx context.Context, id uint64, at time.Time) error
 	}
 
-	ApiClient interface {
-		GetSongInfoWithDetails(group string, song string) (*model.SongInfo, error)
+	// Provider supplies the details of songs, see enrich.
+	Provider interface {
+		Name() string
+		GetSongInfoWithDetails(ctx context.Context, group string, song string) (*model.SongInfo, error)
+		BreakerState() breaker.State
+	}
+
+	// cachedProvider is a provider whose answers are cached.
+	cachedProvider interface {
+		CacheStats() model.CacheStats
 	}
 )
 
 type SongLibraryService struct {
 	songRepo  SongStorage
-	apiClient ApiClient
+	providers []Provider
+	config    *config.Config
+	jobs      *jobPool
+	runs      *enrichmentRuns
 }
 
-func NewSongLibraryService(songRepo SongStorage, apiClient ApiClient) *SongLibraryService {
+// NewSongLibraryService returns the service looking up the song details
+// from the providers in the given order.
+func NewSongLibraryService(songRepo SongStorage, providers []Provider, config *config.Config) *SongLibraryService {
 	return &SongLibraryService{
 		songRepo:  songRepo,
-		apiClient: apiClient,
+		providers: providers,
+		config:    config,
+		jobs:      newJobPool(),
+		runs:      &enrichmentRuns{},
 	}
 }
 
-func (sl *SongLibraryService) Get(id uint64) (*model.SongInfo, error) {
-	return sl.songRepo.Get(id)
+func (sl *SongLibraryService) Get(ctx context.Context, id uint64) (*model.SongInfo, error) {
+	return sl.songRepo.Get(ctx, id)
 }
 
-func (sl *SongLibraryService) GetAll(filters model.SongFilters) ([]*model.SongOut, error) {
-	songs, err := sl.songRepo.GetAll(filters)
-	if err != nil {
-		return nil, err
-	}
-
-	songOuts := make([]*model.SongOut, 0, len(songs))
-	for _, song := range songs {
-		songOuts = append(songOuts, &model.SongOut{
-			ID:          song.ID,
-			Group:       song.Group,
-			Song:        song.Song,
-			ReleaseDate: song.ReleaseDate,
-			Link:        song.Link,
-			TotalVerses: uint(len(song.Text)),
-		})
+func (sl *SongLibraryService) GetAll(ctx context.Context, filters model.SongFilters) (*model.Songs, error) {
+	if filters.Match == model.MatchFuzzy && filters.Threshold == nil {
+		threshold := sl.config.FuzzyThreshold
+		filters.Threshold = &threshold
 	}
-	return songOuts, nil
+	return sl.songRepo.GetAll(ctx, filters)
 }
 
-func (sl *SongLibraryService) GetText(filters model.SongTextFilters) (*string, error) {
+func (sl *SongLibraryService) GetText(ctx context.Context, filters model.SongTextFilters) (*string, error) {
 	if filters.Verse == 0 {
-		return sl.songRepo.GetFullText(filters.ID)
+		return sl.songRepo.GetFullText(ctx, filters.ID)
 	} else {
-		return sl.songRepo.GetText(filters)
+		return sl.songRepo.GetText(ctx, filters)
+	}
+}
+
+// GetVerses returns the verses of the song with their types, or only the
+// requested one if a verse number is set.
+func (sl *SongLibraryService) GetVerses(ctx context.Context, filters model.SongTextFilters) ([]model.Verse, error) {
+	verses, err := sl.songRepo.GetVerses(ctx, filters.ID)
+	if err != nil || filters.Verse == 0 {
+		return verses, err
+	}
+
+	out := []model.Verse{}
+	for _, verse := range verses {
+		if verse.Position == filters.Verse {
+			out = append(out, verse)
+		}
 	}
+	return out, nil
 }
 
-func (sl *SongLibraryService) Insert(group string, song string) (*model.SongInfo, error) {
-	songInfo, err := sl.apiClient.GetSongInfoWithDetails(group, song)
+func (sl *SongLibraryService) Search(ctx context.Context, filters model.SongSearchFilters) ([]*model.SongSearchResult, error) {
+	return sl.songRepo.Search(ctx, filters)
+}
+
+// Insert adds the song with the details found by the providers. If the
+// group already has a song with this title DuplicateSongError is returned,
+// unless onConflict is "update": the details of the existing song are then
+// fetched again, and it is returned as is if the providers no longer know it.
+// external.ErrUnknownSong is returned if there are no details to add a new
+// song with.
+func (sl *SongLibraryService) Insert(ctx context.Context, group string, song string, editor string, onConflict string) (*model.SongInfo, error) {
+	songInfo, status, err := sl.insert(ctx, group, song, editor, onConflict)
+	if err == nil && status == model.BatchStatusNotFound && songInfo == nil {
+		return nil, external.ErrUnknownSong
+	}
+	return songInfo, err
+}
+
+// insert implements Insert and also tells what has been done with the song,
+// as one of the batch statuses.
+func (sl *SongLibraryService) insert(ctx context.Context, group string, song string, editor string, onConflict string) (*model.SongInfo, string, error) {
+	existing, err := sl.getExisting(ctx, group, song, onConflict)
+	if err != nil {
+		return nil, "", err
+	}
+	if existing != nil {
+		// the details of the existing song are fetched again
+		// rather than taken from the cache
+		group, song = existing.Group, existing.Song
+		ctx = cache.Refresh(ctx)
+	}
 
-	logger.PrintDebug("info from external API", map[string]any{
+	songInfo, err := sl.enrich(ctx, group, song)
+
+	logger.PrintDebug("info from providers", map[string]any{
 		"songInfo": songInfo,
 	})
 
@@ -84,23 +186,135 @@ func (sl *SongLibraryService) Insert(group string, song string) (*model.SongInfo
 				"error": err,
 			})
 		} else {
-			return nil, err
+			return nil, "", err
 		}
 	}
 
-	if songInfo != nil {
-		err = sl.songRepo.Insert(songInfo)
-		if err != nil {
-			return nil, err
+	if songInfo == nil {
+		// nothing is known about the song, the existing one is kept as is
+		return existing, model.BatchStatusNotFound, nil
+	}
+	songInfo.Language = detectLanguage(songInfo.Text)
+	enrichedAt := time.Now()
+	songInfo.EnrichedAt = &enrichedAt
+
+	return sl.save(ctx, existing, songInfo, editor, onConflict)
+}
+
+// Create adds the song with the details given by the editor, without asking
+// the providers: all of them are recorded as manual. Conflicts with an
+// existing song of the group are resolved like by Insert, its details being
+// replaced with the given ones on "update". The lyrics decide the language
+// if it is not set.
+func (sl *SongLibraryService) Create(ctx context.Context, song *model.SongInfo, editor string, onConflict string) (*model.SongInfo, error) {
+	existing, err := sl.getExisting(ctx, song.Group, song.Song, onConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	if song.Text == nil {
+		song.Text = []string{}
+	}
+	if song.Language == "" {
+		song.Language = detectLanguage(song.Text)
+	}
+	song.Sources = map[string]string{
+		model.FieldReleaseDate: model.SourceManual,
+		model.FieldLink:        model.SourceManual,
+		model.FieldText:        model.SourceManual,
+	}
+
+	songInfo, _, err := sl.save(ctx, existing, song, editor, onConflict)
+	return songInfo, err
+}
+
+// getExisting returns the song of the group with the given title, which can
+// only be updated if onConflict is "update", or nil if there is none.
+func (sl *SongLibraryService) getExisting(ctx context.Context, group string, song string, onConflict string) (*model.SongInfo, error) {
+	existing, err := sl.songRepo.GetByTitle(ctx, group, song)
+	switch {
+	case err == nil && onConflict == model.OnConflictUpdate:
+		return existing, nil
+	case err == nil:
+		return nil, &db.DuplicateSongError{ID: existing.ID}
+	case errors.Is(err, db.ErrRecordNotFound):
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+// save inserts the song with the details, or updates the existing one with
+// them, and tells which of the batch statuses applies.
+func (sl *SongLibraryService) save(ctx context.Context, existing *model.SongInfo, details *model.SongInfo, editor string, onConflict string) (*model.SongInfo, string, error) {
+	if existing == nil {
+		err := sl.songRepo.Insert(ctx, details, model.SongChange{Editor: editor})
+
+		var duplicate *db.DuplicateSongError
+		switch {
+		case errors.As(err, &duplicate) && onConflict == model.OnConflictUpdate:
+			// the song has been added since it was looked up
+			existing, err = sl.songRepo.Get(ctx, duplicate.ID)
+			if err != nil {
+				return nil, "", err
+			}
+		case err != nil:
+			return nil, "", err
+		default:
+			return details, model.BatchStatusCreated, nil
+		}
+	}
+
+	songInfo, err := sl.refresh(ctx, existing, details, editor)
+	if err != nil {
+		return nil, "", err
+	}
+	return songInfo, model.BatchStatusUpdated, nil
+}
+
+// refresh updates the existing song with the details fetched from the
+// providers or given by the editor, keeping its group and title and the
+// details edited by hand, see mergeDetails. No revision is recorded if
+// nothing has changed.
+func (sl *SongLibraryService) refresh(ctx context.Context, song *model.SongInfo, details *model.SongInfo, editor string) (*model.SongInfo, error) {
+	updated, _ := mergeDetails(song, details)
+
+	if reflect.DeepEqual(updated, *song) {
+		if details.EnrichedAt != nil {
+			err := sl.songRepo.SetEnrichedAt(ctx, song.ID, *details.EnrichedAt)
+			if err != nil {
+				return nil, err
+			}
+			song.EnrichedAt = details.EnrichedAt
 		}
+		return song, nil
 	}
 
-	return songInfo, nil
+	if details.EnrichedAt != nil {
+		updated.EnrichedAt = details.EnrichedAt
+	}
+	err := sl.songRepo.Update(ctx, &updated, model.SongChange{Editor: editor})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
 }
 
-func (sl *SongLibraryService) Update(song *model.SongInfo) error {
+// Update saves the song edited through the API, the changed details
+// are recorded as manual ones in its sources.
+func (sl *SongLibraryService) Update(ctx context.Context, song *model.SongInfo, change model.SongChange) error {
 	if !reflect.DeepEqual(*song, model.SongInfo{}) {
-		err := sl.songRepo.Update(song)
+		current, err := sl.songRepo.Get(ctx, song.ID)
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			// the song has been deleted since it was fetched
+			return db.ErrEditConflict
+		case err != nil:
+			return err
+		}
+		markManual(song, current)
+
+		err = sl.songRepo.Update(ctx, song, change)
 		if err != nil {
 			return err
 		}
@@ -109,6 +323,188 @@ func (sl *SongLibraryService) Update(song *model.SongInfo) error {
 	return nil
 }
 
-func (sl *SongLibraryService) Delete(id uint64) error {
-	return sl.songRepo.Delete(id)
+func (sl *SongLibraryService) Delete(ctx context.Context, id uint64, version uint) error {
+	return sl.songRepo.Delete(ctx, id, version)
+}
+
+func (sl *SongLibraryService) GetTrash(ctx context.Context, filters model.TrashFilters) ([]*model.DeletedSongOut, error) {
+	return sl.songRepo.GetTrash(ctx, filters)
+}
+
+func (sl *SongLibraryService) Restore(ctx context.Context, id uint64) (*model.SongInfo, error) {
+	err := sl.songRepo.Restore(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sl.songRepo.Get(ctx, id)
+}
+
+// ProvidersHealth returns the state of the circuit breakers guarding the
+// providers, in their order, along with the counters of their caches.
+func (sl *SongLibraryService) ProvidersHealth() []model.ProviderHealth {
+	health := make([]model.ProviderHealth, 0, len(sl.providers))
+	for _, p := range sl.providers {
+		ph := model.ProviderHealth{Name: p.Name(), State: p.BreakerState()}
+		if cached, ok := p.(cachedProvider); ok {
+			stats := cached.CacheStats()
+			ph.Cache = &stats
+		}
+		health = append(health, ph)
+	}
+	return health
+}
+
+// Purge permanently deletes the songs that have been in the trash
+// longer than the configured retention period.
+func (sl *SongLibraryService) Purge(ctx context.Context) (int64, error) {
+	return sl.songRepo.Purge(ctx, time.Now().Add(-sl.config.TrashRetention))
+}
+
+func (sl *SongLibraryService) GetArtist(ctx context.Context, id uint64) (*model.Artist, error) {
+	return sl.songRepo.GetArtist(ctx, id)
+}
+
+func (sl *SongLibraryService) GetArtists(ctx context.Context, filters model.ArtistFilters) ([]*model.Artist, error) {
+	return sl.songRepo.GetArtists(ctx, filters)
+}
+
+func (sl *SongLibraryService) GetRevisions(ctx context.Context, filters model.SongRevisionFilters) ([]*model.SongRevisionOut, error) {
+	return sl.songRepo.GetRevisions(ctx, filters)
+}
+
+func (sl *SongLibraryService) GetRevision(ctx context.Context, songID uint64, revision uint) (*model.SongRevision, error) {
+	return sl.songRepo.GetRevision(ctx, songID, revision)
+}
+
+// Diff compares two revisions of the song. A zero from revision
+// stands for an empty song, so the diff shows the whole "to" revision as added.
+func (sl *SongLibraryService) Diff(ctx context.Context, songID uint64, from uint, to uint) (*model.SongDiff, error) {
+	fromRev := &model.SongRevision{SongID: songID}
+	if from != 0 {
+		var err error
+		fromRev, err = sl.songRepo.GetRevision(ctx, songID, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+	toRev, err := sl.songRepo.GetRevision(ctx, songID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffRevisions(fromRev, toRev), nil
+}
+
+func (sl *SongLibraryService) GetAlbum(ctx context.Context, id uint64) (*model.Album, error) {
+	return sl.songRepo.GetAlbum(ctx, id)
+}
+
+func (sl *SongLibraryService) GetAlbums(ctx context.Context, filters model.AlbumFilters) ([]*model.AlbumOut, error) {
+	return sl.songRepo.GetAlbums(ctx, filters)
+}
+
+func (sl *SongLibraryService) InsertAlbum(ctx context.Context, album *model.Album) error {
+	return sl.songRepo.InsertAlbum(ctx, album)
+}
+
+func (sl *SongLibraryService) UpdateAlbum(ctx context.Context, album *model.Album) error {
+	return sl.songRepo.UpdateAlbum(ctx, album)
+}
+
+func (sl *SongLibraryService) DeleteAlbum(ctx context.Context, id uint64) error {
+	return sl.songRepo.DeleteAlbum(ctx, id)
+}
+
+func (sl *SongLibraryService) SetAlbumTrack(ctx context.Context, albumID uint64, track model.AlbumTrackInput) (*model.Album, error) {
+	err := sl.songRepo.SetAlbumTrack(ctx, albumID, track)
+	if err != nil {
+		return nil, err
+	}
+	return sl.songRepo.GetAlbum(ctx, albumID)
+}
+
+func (sl *SongLibraryService) RemoveAlbumTrack(ctx context.Context, albumID uint64, songID uint64) (*model.Album, error) {
+	err := sl.songRepo.RemoveAlbumTrack(ctx, albumID, songID)
+	if err != nil {
+		return nil, err
+	}
+	return sl.songRepo.GetAlbum(ctx, albumID)
+}
+
+func (sl *SongLibraryService) GetPlaylist(ctx context.Context, id uint64) (*model.Playlist, error) {
+	return sl.songRepo.GetPlaylist(ctx, id)
+}
+
+func (sl *SongLibraryService) GetPlaylists(ctx context.Context, filters model.PlaylistFilters) ([]*model.PlaylistOut, error) {
+	return sl.songRepo.GetPlaylists(ctx, filters)
+}
+
+func (sl *SongLibraryService) InsertPlaylist(ctx context.Context, playlist *model.Playlist) error {
+	return sl.songRepo.InsertPlaylist(ctx, playlist)
+}
+
+func (sl *SongLibraryService) UpdatePlaylist(ctx context.Context, playlist *model.Playlist) error {
+	return sl.songRepo.UpdatePlaylist(ctx, playlist)
+}
+
+func (sl *SongLibraryService) DeletePlaylist(ctx context.Context, id uint64) error {
+	return sl.songRepo.DeletePlaylist(ctx, id)
+}
+
+func (sl *SongLibraryService) AddPlaylistEntry(ctx context.Context, playlistID uint64, entry model.PlaylistEntryInput) (*model.Playlist, error) {
+	err := sl.songRepo.AddPlaylistEntry(ctx, playlistID, entry)
+	if err != nil {
+		return nil, err
+	}
+	return sl.songRepo.GetPlaylist(ctx, playlistID)
+}
+
+func (sl *SongLibraryService) MovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64, position uint) (*model.Playlist, error) {
+	err := sl.songRepo.MovePlaylistEntry(ctx, playlistID, entryID, position)
+	if err != nil {
+		return nil, err
+	}
+	return sl.songRepo.GetPlaylist(ctx, playlistID)
+}
+
+func (sl *SongLibraryService) RemovePlaylistEntry(ctx context.Context, playlistID uint64, entryID uint64) (*model.Playlist, error) {
+	err := sl.songRepo.RemovePlaylistEntry(ctx, playlistID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	return sl.songRepo.GetPlaylist(ctx, playlistID)
+}
+
+// markManual records the details of the song that differ from the current
+// ones as edited manually.
+func markManual(song *model.SongInfo, current *model.SongInfo) {
+	changed := map[string]bool{
+		model.FieldReleaseDate: song.ReleaseDate != current.ReleaseDate,
+		model.FieldLink:        song.Link != current.Link,
+		model.FieldText:        !slices.Equal(song.Text, current.Text),
+	}
+
+	sources := maps.Clone(current.Sources)
+	for field, ok := range changed {
+		if ok {
+			if sources == nil {
+				sources = make(map[string]string)
+			}
+			sources[field] = model.SourceManual
+		}
+	}
+	song.Sources = sources
+}
+
+// detectLanguage picks the text search configuration for the lyrics:
+// russian if they contain Cyrillic letters, english otherwise.
+func detectLanguage(text []string) string {
+	for _, verse := range text {
+		for _, r := range verse {
+			if unicode.Is(unicode.Cyrillic, r) {
+				return "russian"
+			}
+		}
+	}
+	return "english"
 }
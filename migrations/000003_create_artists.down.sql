@@ -0,0 +1,13 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS "group" text;
+
+UPDATE songs s
+SET "group" = a.name
+FROM artists a
+WHERE a.artist_id = s.artist_id;
+
+ALTER TABLE songs ALTER COLUMN "group" SET NOT NULL;
+
+DROP INDEX IF EXISTS songs_artist_id_idx;
+ALTER TABLE songs DROP COLUMN IF EXISTS artist_id;
+
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS artists(
+    artist_id bigserial PRIMARY KEY,
+    name text NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS artists_name_idx ON artists (LOWER(name));
+
+INSERT INTO artists (name)
+SELECT DISTINCT ON (LOWER(regexp_replace(TRIM("group"), '\s+', ' ', 'g'))) regexp_replace(TRIM("group"), '\s+', ' ', 'g')
+FROM songs
+ORDER BY LOWER(regexp_replace(TRIM("group"), '\s+', ' ', 'g')), song_id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS artist_id bigint REFERENCES artists (artist_id);
+
+UPDATE songs s
+SET artist_id = a.artist_id
+FROM artists a
+WHERE LOWER(a.name) = LOWER(regexp_replace(TRIM(s."group"), '\s+', ' ', 'g'));
+
+ALTER TABLE songs ALTER COLUMN artist_id SET NOT NULL;
+ALTER TABLE songs DROP COLUMN IF EXISTS "group";
+
+CREATE INDEX IF NOT EXISTS songs_artist_id_idx ON songs (artist_id);
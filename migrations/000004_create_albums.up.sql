@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS albums(
+    album_id bigserial PRIMARY KEY,
+    artist_id bigint NOT NULL REFERENCES artists (artist_id),
+    title text NOT NULL,
+    release_date text NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS albums_artist_id_idx ON albums (artist_id);
+
+CREATE TABLE IF NOT EXISTS album_tracks(
+    album_id bigint NOT NULL REFERENCES albums (album_id) ON DELETE CASCADE,
+    song_id bigint NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0),
+    PRIMARY KEY (album_id, song_id),
+    CONSTRAINT album_tracks_position_key UNIQUE (album_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX IF NOT EXISTS album_tracks_song_id_idx ON album_tracks (song_id);
@@ -0,0 +1,27 @@
+DROP INDEX IF EXISTS songs_release_date_idx;
+
+ALTER TABLE songs ADD COLUMN release_date_text text;
+
+UPDATE songs
+SET release_date_text = COALESCE(to_char(release_date, CASE release_precision
+    WHEN 'year' THEN 'YYYY'
+    WHEN 'month' THEN 'MM.YYYY'
+    ELSE 'DD.MM.YYYY'
+END), '');
+
+ALTER TABLE songs DROP COLUMN release_date, DROP COLUMN release_precision;
+ALTER TABLE songs RENAME COLUMN release_date_text TO release_date;
+ALTER TABLE songs ALTER COLUMN release_date SET NOT NULL;
+
+ALTER TABLE albums ADD COLUMN release_date_text text;
+
+UPDATE albums
+SET release_date_text = COALESCE(to_char(release_date, CASE release_precision
+    WHEN 'year' THEN 'YYYY'
+    WHEN 'month' THEN 'MM.YYYY'
+    ELSE 'DD.MM.YYYY'
+END), '');
+
+ALTER TABLE albums DROP COLUMN release_date, DROP COLUMN release_precision;
+ALTER TABLE albums RENAME COLUMN release_date_text TO release_date;
+ALTER TABLE albums ALTER COLUMN release_date SET NOT NULL;
@@ -0,0 +1,43 @@
+CREATE FUNCTION pg_temp.parse_release_date(value text, OUT parsed date, OUT parsed_precision text) AS $$
+BEGIN
+    IF value ~ '^[0-3][0-9]\.[0-1][0-9]\.[0-9]{4}$' THEN
+        parsed := to_date(value, 'DD.MM.YYYY');
+        parsed_precision := 'day';
+    ELSIF value ~ '^[0-1][0-9]\.[0-9]{4}$' THEN
+        parsed := to_date('01.' || value, 'DD.MM.YYYY');
+        parsed_precision := 'month';
+    ELSIF value ~ '^[0-9]{4}$' THEN
+        parsed := to_date('01.01.' || value, 'DD.MM.YYYY');
+        parsed_precision := 'year';
+    END IF;
+EXCEPTION WHEN others THEN
+    parsed := NULL;
+    parsed_precision := NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE songs RENAME COLUMN release_date TO release_date_text;
+ALTER TABLE songs
+    ADD COLUMN release_date date,
+    ADD COLUMN release_precision text CHECK (release_precision IN ('day', 'month', 'year'));
+
+UPDATE songs
+SET (release_date, release_precision) = (
+    SELECT parsed, parsed_precision FROM pg_temp.parse_release_date(release_date_text)
+);
+
+ALTER TABLE songs DROP COLUMN release_date_text;
+
+ALTER TABLE albums RENAME COLUMN release_date TO release_date_text;
+ALTER TABLE albums
+    ADD COLUMN release_date date,
+    ADD COLUMN release_precision text CHECK (release_precision IN ('day', 'month', 'year'));
+
+UPDATE albums
+SET (release_date, release_precision) = (
+    SELECT parsed, parsed_precision FROM pg_temp.parse_release_date(release_date_text)
+);
+
+ALTER TABLE albums DROP COLUMN release_date_text;
+
+CREATE INDEX IF NOT EXISTS songs_release_date_idx ON songs (release_date);
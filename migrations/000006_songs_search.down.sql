@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS songs_search_vector_idx;
+DROP TRIGGER IF EXISTS songs_search_vector_trigger ON songs;
+DROP FUNCTION IF EXISTS songs_search_vector_update();
+
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector, DROP COLUMN IF EXISTS language;
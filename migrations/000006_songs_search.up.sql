@@ -0,0 +1,24 @@
+ALTER TABLE songs
+    ADD COLUMN language text NOT NULL DEFAULT 'simple' CHECK (language IN ('simple', 'english', 'russian')),
+    ADD COLUMN search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION songs_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector(NEW.language::regconfig, COALESCE(NEW.song, '')), 'A') ||
+        setweight(to_tsvector(NEW.language::regconfig, COALESCE(array_to_string(NEW.song_text, E'\n\n'), '')), 'D');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_search_vector_trigger
+BEFORE INSERT OR UPDATE ON songs
+FOR EACH ROW EXECUTE FUNCTION songs_search_vector_update();
+
+UPDATE songs
+SET language = CASE
+    WHEN COALESCE(array_to_string(song_text, ' '), '') ~ '[А-Яа-яЁё]' THEN 'russian'
+    ELSE 'english'
+END;
+
+CREATE INDEX IF NOT EXISTS songs_search_vector_idx ON songs USING GIN (search_vector);
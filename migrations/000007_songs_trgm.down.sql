@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS songs_song_trgm_idx;
+DROP INDEX IF EXISTS artists_name_trgm_idx;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS song_revisions(
+    song_id bigint NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    revision integer NOT NULL,
+    "group" text NOT NULL,
+    song text NOT NULL,
+    release_date date,
+    release_precision text,
+    song_text text[],
+    link text,
+    language text NOT NULL,
+    action text NOT NULL CHECK (action IN ('create', 'update', 'revert', 'snapshot')),
+    reverted_from integer,
+    editor text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (song_id, revision)
+);
+
+CREATE OR REPLACE FUNCTION song_revisions_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'song revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER song_revisions_immutable_trigger
+BEFORE UPDATE ON song_revisions
+FOR EACH ROW EXECUTE FUNCTION song_revisions_immutable();
+
+-- The existing songs are recorded as they are, their history is unknown.
+INSERT INTO song_revisions (song_id, revision, "group", song, release_date, release_precision, song_text, link, language, action)
+SELECT s.song_id, s.version, a.name, s.song, s.release_date, s.release_precision, s.song_text, s.link, s.language, 'snapshot'
+FROM songs s
+JOIN artists a ON a.artist_id = s.artist_id;
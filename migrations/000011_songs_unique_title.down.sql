@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS songs_artist_song_idx;
+
+-- The duplicates moved to the trash are restored, unless they have been
+-- restored or deleted again since.
+UPDATE songs s
+SET deleted_at = NULL
+FROM song_title_duplicates d
+WHERE d.song_id = s.song_id AND s.deleted_at = d.deleted_at;
+
+DROP TABLE IF EXISTS song_title_duplicates;
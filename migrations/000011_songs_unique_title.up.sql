@@ -0,0 +1,29 @@
+-- Live songs duplicating an earlier song of the same group are moved to the
+-- trash, so that they can still be looked at and purged. They are recorded
+-- for the down migration to restore them.
+CREATE TABLE IF NOT EXISTS song_title_duplicates(
+    song_id bigint PRIMARY KEY REFERENCES songs (song_id) ON DELETE CASCADE,
+    deleted_at timestamptz NOT NULL
+);
+
+INSERT INTO song_title_duplicates (song_id, deleted_at)
+SELECT s.song_id, now()
+FROM songs s
+WHERE s.deleted_at IS NULL
+AND EXISTS (
+    SELECT 1
+    FROM songs d
+    WHERE d.deleted_at IS NULL
+    AND d.artist_id = s.artist_id
+    AND LOWER(TRIM(regexp_replace(d.song, '\s+', ' ', 'g'))) = LOWER(TRIM(regexp_replace(s.song, '\s+', ' ', 'g')))
+    AND d.song_id < s.song_id
+);
+
+UPDATE songs s
+SET deleted_at = d.deleted_at
+FROM song_title_duplicates d
+WHERE d.song_id = s.song_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS songs_artist_song_idx
+ON songs (artist_id, LOWER(TRIM(regexp_replace(song, '\s+', ' ', 'g'))))
+WHERE deleted_at IS NULL;
@@ -0,0 +1,24 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS song_text text[];
+
+UPDATE songs s
+SET song_text = ARRAY(
+    SELECT v.text
+    FROM song_verses v
+    WHERE v.song_id = s.song_id
+    ORDER BY v.position
+);
+
+DROP TRIGGER IF EXISTS song_verses_search_vector_trigger ON song_verses;
+DROP FUNCTION IF EXISTS song_verses_search_vector_update();
+DROP TABLE IF EXISTS song_verses;
+
+CREATE OR REPLACE FUNCTION songs_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector(NEW.language::regconfig, COALESCE(NEW.song, '')), 'A') ||
+        setweight(to_tsvector(NEW.language::regconfig, COALESCE(array_to_string(NEW.song_text, E'\n\n'), '')), 'D');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS songs_search_vector(bigint, text, text);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS song_verses(
+    song_id bigint NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0),
+    type text NOT NULL DEFAULT 'verse' CHECK (type IN ('verse', 'chorus', 'bridge', 'intro', 'outro')),
+    label text NOT NULL DEFAULT '',
+    text text NOT NULL,
+    PRIMARY KEY (song_id, position)
+);
+
+INSERT INTO song_verses (song_id, position, text)
+SELECT s.song_id, v.ord, v.verse
+FROM songs s, unnest(s.song_text) WITH ORDINALITY AS v(verse, ord)
+WHERE v.verse IS NOT NULL;
+
+-- The lyrics are indexed from the verses now, the vector of a song is
+-- recomputed whenever its title, language or verses change.
+CREATE OR REPLACE FUNCTION songs_search_vector(bigint, text, text) RETURNS tsvector AS $$
+    SELECT
+        setweight(to_tsvector($3::regconfig, COALESCE($2, '')), 'A') ||
+        setweight(to_tsvector($3::regconfig, COALESCE((
+            SELECT string_agg(v.text, E'\n\n' ORDER BY v.position)
+            FROM song_verses v
+            WHERE v.song_id = $1
+        ), '')), 'D');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION songs_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := songs_search_vector(NEW.song_id, NEW.song, NEW.language);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION song_verses_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE songs s
+    SET search_vector = songs_search_vector(s.song_id, s.song, s.language)
+    WHERE s.song_id IN (NEW.song_id, OLD.song_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER song_verses_search_vector_trigger
+AFTER INSERT OR UPDATE OR DELETE ON song_verses
+FOR EACH ROW EXECUTE FUNCTION song_verses_search_vector_update();
+
+ALTER TABLE songs DROP COLUMN IF EXISTS song_text;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS jobs(
+    job_id bigserial PRIMARY KEY,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    "group" text NOT NULL,
+    song text NOT NULL,
+    editor text NOT NULL DEFAULT '',
+    on_conflict text NOT NULL DEFAULT 'fail',
+    result text NOT NULL DEFAULT '',
+    song_id bigint REFERENCES songs (song_id) ON DELETE SET NULL,
+    error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS jobs_pending_idx ON jobs (job_id) WHERE status = 'pending';
@@ -0,0 +1,42 @@
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS sources jsonb NOT NULL DEFAULT '{}';
+
+-- The existing songs are given the fields edited by hand since they were
+-- created, the ones a later revision has changed. The refreshes of
+-- onConflict=update are told from the edits by nothing, so the fields they
+-- have changed are kept as manual too. The songs older than the history,
+-- whose first revision is a snapshot, are of unknown sources: the details
+-- they have are kept as manual, the missing ones are left to the providers.
+UPDATE songs s
+SET sources = m.sources
+FROM (
+    SELECT f.song_id, jsonb_object_agg(f.field, 'manual'::text) AS sources
+    FROM (
+        SELECT r.song_id, c.field
+        FROM song_revisions r
+        JOIN LATERAL (
+            SELECT p.*
+            FROM song_revisions p
+            WHERE p.song_id = r.song_id AND p.revision < r.revision
+            ORDER BY p.revision DESC
+            LIMIT 1
+        ) p ON true
+        CROSS JOIN LATERAL (VALUES
+            ('releaseDate', r.release_date IS DISTINCT FROM p.release_date OR r.release_precision IS DISTINCT FROM p.release_precision),
+            ('link', r.link IS DISTINCT FROM p.link),
+            ('text', r.song_text IS DISTINCT FROM p.song_text)
+        ) AS c(field, changed)
+        WHERE c.changed
+        UNION
+        SELECT s.song_id, c.field
+        FROM songs s
+        JOIN song_revisions r ON r.song_id = s.song_id AND r.action = 'snapshot'
+        CROSS JOIN LATERAL (VALUES
+            ('releaseDate', s.release_date IS NOT NULL),
+            ('link', COALESCE(s.link, '') <> ''),
+            ('text', EXISTS (SELECT 1 FROM song_verses v WHERE v.song_id = s.song_id))
+        ) AS c(field, known)
+        WHERE c.known
+    ) f
+    GROUP BY f.song_id
+) m
+WHERE m.song_id = s.song_id;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS song_info_cache(
+    key text PRIMARY KEY,
+    song_info jsonb,
+    expires_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS song_info_cache_expires_at_idx ON song_info_cache (expires_at);
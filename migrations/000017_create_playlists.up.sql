@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS playlists(
+    playlist_id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT ''
+);
+
+-- A song may be in a playlist more than once, so the entries have their own ID.
+CREATE TABLE IF NOT EXISTS playlist_entries(
+    entry_id bigserial PRIMARY KEY,
+    playlist_id bigint NOT NULL REFERENCES playlists (playlist_id) ON DELETE CASCADE,
+    song_id bigint NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0),
+    CONSTRAINT playlist_entries_position_key UNIQUE (playlist_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX IF NOT EXISTS playlist_entries_song_id_idx ON playlist_entries (song_id);
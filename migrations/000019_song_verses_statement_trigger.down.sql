@@ -0,0 +1,16 @@
+DROP TRIGGER IF EXISTS song_verses_search_vector_insert_trigger ON song_verses;
+DROP TRIGGER IF EXISTS song_verses_search_vector_update_trigger ON song_verses;
+DROP TRIGGER IF EXISTS song_verses_search_vector_delete_trigger ON song_verses;
+
+CREATE OR REPLACE FUNCTION song_verses_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE songs s
+    SET search_vector = songs_search_vector(s.song_id, s.song, s.language)
+    WHERE s.song_id IN (NEW.song_id, OLD.song_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER song_verses_search_vector_trigger
+AFTER INSERT OR UPDATE OR DELETE ON song_verses
+FOR EACH ROW EXECUTE FUNCTION song_verses_search_vector_update();
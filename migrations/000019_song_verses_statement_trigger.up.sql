@@ -0,0 +1,39 @@
+DROP TRIGGER IF EXISTS song_verses_search_vector_trigger ON song_verses;
+
+-- The vector of a song is recomputed once per statement rather than once per
+-- verse, replacing the verses of a song would otherwise recompute it for every
+-- one of them. Transition tables are only allowed for a single event, hence a
+-- trigger per event.
+CREATE OR REPLACE FUNCTION song_verses_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE songs s
+        SET search_vector = songs_search_vector(s.song_id, s.song, s.language)
+        WHERE s.song_id IN (SELECT song_id FROM new_verses);
+    ELSIF TG_OP = 'UPDATE' THEN
+        UPDATE songs s
+        SET search_vector = songs_search_vector(s.song_id, s.song, s.language)
+        WHERE s.song_id IN (SELECT song_id FROM new_verses UNION SELECT song_id FROM old_verses);
+    ELSE
+        UPDATE songs s
+        SET search_vector = songs_search_vector(s.song_id, s.song, s.language)
+        WHERE s.song_id IN (SELECT song_id FROM old_verses);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER song_verses_search_vector_insert_trigger
+AFTER INSERT ON song_verses
+REFERENCING NEW TABLE AS new_verses
+FOR EACH STATEMENT EXECUTE FUNCTION song_verses_search_vector_update();
+
+CREATE TRIGGER song_verses_search_vector_update_trigger
+AFTER UPDATE ON song_verses
+REFERENCING OLD TABLE AS old_verses NEW TABLE AS new_verses
+FOR EACH STATEMENT EXECUTE FUNCTION song_verses_search_vector_update();
+
+CREATE TRIGGER song_verses_search_vector_delete_trigger
+AFTER DELETE ON song_verses
+REFERENCING OLD TABLE AS old_verses
+FOR EACH STATEMENT EXECUTE FUNCTION song_verses_search_vector_update();
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS song_revisions;
+DROP TABLE IF EXISTS album_tracks;
+DROP TABLE IF EXISTS albums;
+DROP TABLE IF EXISTS songs;
+DROP TABLE IF EXISTS artists;
@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS artists(
+    artist_id integer PRIMARY KEY,
+    name text NOT NULL,
+    -- the normalized lowercase name, SQLite's LOWER only folds ASCII letters
+    name_key text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS songs(
+    song_id integer PRIMARY KEY,
+    artist_id integer NOT NULL REFERENCES artists (artist_id),
+    song text NOT NULL,
+    -- the first day of the period, YYYY-MM-DD
+    release_date text,
+    release_precision text CHECK (release_precision IN ('day', 'month', 'year')),
+    -- JSON array of the verses
+    song_text text CHECK (song_text IS NULL OR json_type(song_text) = 'array'),
+    link text,
+    language text NOT NULL DEFAULT 'simple' CHECK (language IN ('simple', 'english', 'russian')),
+    version integer NOT NULL DEFAULT 1,
+    deleted_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS songs_artist_id_idx ON songs (artist_id);
+CREATE INDEX IF NOT EXISTS songs_release_date_idx ON songs (release_date);
+CREATE INDEX IF NOT EXISTS songs_deleted_at_idx ON songs (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS albums(
+    album_id integer PRIMARY KEY,
+    artist_id integer NOT NULL REFERENCES artists (artist_id),
+    title text NOT NULL,
+    release_date text,
+    release_precision text CHECK (release_precision IN ('day', 'month', 'year'))
+);
+
+CREATE INDEX IF NOT EXISTS albums_artist_id_idx ON albums (artist_id);
+
+-- Unlike Postgres, SQLite cannot defer unique constraints, and shifting the
+-- positions would violate one, so they are only kept unique by the repository.
+CREATE TABLE IF NOT EXISTS album_tracks(
+    album_id integer NOT NULL REFERENCES albums (album_id) ON DELETE CASCADE,
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0),
+    PRIMARY KEY (album_id, song_id)
+);
+
+CREATE INDEX IF NOT EXISTS album_tracks_song_id_idx ON album_tracks (song_id);
+
+CREATE TABLE IF NOT EXISTS song_revisions(
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    revision integer NOT NULL,
+    "group" text NOT NULL,
+    song text NOT NULL,
+    release_date text,
+    release_precision text,
+    song_text text,
+    link text,
+    language text NOT NULL,
+    action text NOT NULL CHECK (action IN ('create', 'update', 'revert', 'snapshot')),
+    reverted_from integer,
+    editor text NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL,
+    PRIMARY KEY (song_id, revision)
+);
+
+CREATE TRIGGER IF NOT EXISTS song_revisions_immutable_trigger
+BEFORE UPDATE ON song_revisions
+BEGIN
+    SELECT RAISE(ABORT, 'song revisions are immutable');
+END;
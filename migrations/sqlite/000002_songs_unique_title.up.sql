@@ -0,0 +1,34 @@
+-- The key song titles are unique by within a group: the lowercase title with
+-- whitespace collapsed, set by the repository. The existing songs get it from
+-- name_key, the function the storage registers for the same normalization.
+ALTER TABLE songs ADD COLUMN song_key text NOT NULL DEFAULT '';
+
+UPDATE songs SET song_key = name_key(song);
+
+-- Live songs duplicating an earlier song of the same group are moved to the
+-- trash, so that they can still be looked at and purged. They are recorded
+-- for the down migration to restore them.
+CREATE TABLE IF NOT EXISTS song_title_duplicates(
+    song_id integer PRIMARY KEY REFERENCES songs (song_id) ON DELETE CASCADE,
+    deleted_at timestamp NOT NULL
+);
+
+INSERT INTO song_title_duplicates (song_id, deleted_at)
+SELECT s.song_id, strftime('%Y-%m-%d %H:%M:%f000000', 'now')
+FROM songs s
+WHERE s.deleted_at IS NULL
+AND EXISTS (
+    SELECT 1
+    FROM songs d
+    WHERE d.deleted_at IS NULL
+    AND d.artist_id = s.artist_id
+    AND d.song_key = s.song_key
+    AND d.song_id < s.song_id
+);
+
+UPDATE songs
+SET deleted_at = d.deleted_at
+FROM song_title_duplicates d
+WHERE d.song_id = songs.song_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS songs_artist_song_idx ON songs (artist_id, song_key) WHERE deleted_at IS NULL;
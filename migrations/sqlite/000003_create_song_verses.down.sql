@@ -0,0 +1,10 @@
+ALTER TABLE songs ADD COLUMN song_text text;
+
+UPDATE songs
+SET song_text = (
+    SELECT json_group_array(v.text ORDER BY v.position)
+    FROM song_verses v
+    WHERE v.song_id = songs.song_id
+);
+
+DROP TABLE IF EXISTS song_verses;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS song_verses(
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0),
+    type text NOT NULL DEFAULT 'verse' CHECK (type IN ('verse', 'chorus', 'bridge', 'intro', 'outro')),
+    label text NOT NULL DEFAULT '',
+    text text NOT NULL,
+    PRIMARY KEY (song_id, position)
+);
+
+INSERT INTO song_verses (song_id, position, text)
+SELECT s.song_id, v.key + 1, v.value
+FROM songs s, json_each(s.song_text) AS v
+WHERE s.song_text IS NOT NULL AND v.value IS NOT NULL;
+
+ALTER TABLE songs DROP COLUMN song_text;
@@ -0,0 +1,34 @@
+ALTER TABLE songs ADD COLUMN sources text NOT NULL DEFAULT '{}';
+
+-- The existing songs are given the fields edited by hand since they were
+-- created, the ones a later revision has changed. The refreshes of
+-- onConflict=update are told from the edits by nothing, so the fields they
+-- have changed are kept as manual too. The songs older than the history,
+-- whose first revision is a snapshot, are of unknown sources: the details
+-- they have are kept as manual, the missing ones are left to the providers.
+UPDATE songs
+SET sources = m.sources
+FROM (
+    SELECT c.song_id, json_group_object(c.field, 'manual') AS sources
+    FROM (
+        SELECT DISTINCT r.song_id, f.field
+        FROM song_revisions r
+        JOIN song_revisions p ON p.song_id = r.song_id AND p.revision = (
+            SELECT max(revision) FROM song_revisions WHERE song_id = r.song_id AND revision < r.revision
+        )
+        JOIN (SELECT 'releaseDate' AS field UNION ALL SELECT 'link' UNION ALL SELECT 'text') f ON
+            (f.field = 'releaseDate' AND (r.release_date IS NOT p.release_date OR r.release_precision IS NOT p.release_precision)) OR
+            (f.field = 'link' AND r.link IS NOT p.link) OR
+            (f.field = 'text' AND r.song_text IS NOT p.song_text)
+        UNION
+        SELECT s.song_id, f.field
+        FROM songs s
+        JOIN song_revisions r ON r.song_id = s.song_id AND r.action = 'snapshot'
+        JOIN (SELECT 'releaseDate' AS field UNION ALL SELECT 'link' UNION ALL SELECT 'text') f ON
+            (f.field = 'releaseDate' AND s.release_date IS NOT NULL) OR
+            (f.field = 'link' AND COALESCE(s.link, '') <> '') OR
+            (f.field = 'text' AND EXISTS (SELECT 1 FROM song_verses v WHERE v.song_id = s.song_id))
+    ) c
+    GROUP BY c.song_id
+) AS m
+WHERE m.song_id = songs.song_id;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS playlists(
+    playlist_id integer PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT ''
+);
+
+-- A song may be in a playlist more than once, so the entries have their own ID.
+-- The positions are only kept unique by the repository, like the album tracks.
+CREATE TABLE IF NOT EXISTS playlist_entries(
+    entry_id integer PRIMARY KEY,
+    playlist_id integer NOT NULL REFERENCES playlists (playlist_id) ON DELETE CASCADE,
+    song_id integer NOT NULL REFERENCES songs (song_id) ON DELETE CASCADE,
+    position integer NOT NULL CHECK (position > 0)
+);
+
+CREATE INDEX IF NOT EXISTS playlist_entries_playlist_id_idx ON playlist_entries (playlist_id, position);
+CREATE INDEX IF NOT EXISTS playlist_entries_song_id_idx ON playlist_entries (song_id);
@@ -0,0 +1 @@
+ALTER TABLE songs DROP COLUMN enrich_failed_at;
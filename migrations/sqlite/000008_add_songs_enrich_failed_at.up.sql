@@ -0,0 +1 @@
+ALTER TABLE songs ADD COLUMN enrich_failed_at timestamp;
@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN claims integer NOT NULL DEFAULT 0;
@@ -0,0 +1,150 @@
+// Package breaker implements a circuit breaker, which stops calling a failing
+// service for a while so that callers fail fast instead of waiting for it.
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// States of the breaker.
+const (
+	// StateClosed lets the calls through.
+	StateClosed = "closed"
+	// StateOpen rejects the calls until the cooldown is over.
+	StateOpen = "open"
+	// StateHalfOpen lets a single call through after the cooldown,
+	// its outcome closes or reopens the breaker.
+	StateHalfOpen = "half-open"
+)
+
+// State is a snapshot of the breaker.
+type State struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+}
+
+// Breaker opens after threshold consecutive failures and stays open for the
+// cooldown. Every call allowed must be followed by Success, Failure or Release
+// with the token Allow has returned. It is safe for concurrent use.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	// generation changes whenever the breaker opens, closes or lets a probe
+	// through, so that the outcomes of the earlier calls are told apart.
+	generation uint64
+	// probedAt is when the call probing the half-open breaker was allowed,
+	// another one is allowed if it has not ended within the cooldown.
+	probedAt time.Time
+}
+
+// Token ties the outcome of a call to the state of the breaker
+// in which it was allowed.
+type Token struct {
+	generation uint64
+	probe      bool
+}
+
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+	}
+}
+
+// Allow reports whether a call may be made and returns the token of the call.
+// While the breaker is half-open only one call is allowed at a time.
+func (b *Breaker) Allow() (Token, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state(now) {
+	case StateOpen:
+		return Token{}, false
+	case StateHalfOpen:
+		if !b.probedAt.IsZero() && now.Sub(b.probedAt) < b.cooldown {
+			return Token{}, false
+		}
+		b.probedAt = now
+		b.generation++
+		return Token{generation: b.generation, probe: true}, true
+	}
+	return Token{generation: b.generation}, true
+}
+
+// Success records a successful call. The probe of the half-open breaker
+// closes it, any other call only resets the failures of a closed breaker.
+func (b *Breaker) Success(t Token) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case t.probe && t.generation == b.generation:
+		b.failures = 0
+		b.openedAt = time.Time{}
+		b.probedAt = time.Time{}
+		b.generation++
+	case b.openedAt.IsZero():
+		b.failures = 0
+	}
+}
+
+// Release ends a call that has no outcome, such as one abandoned by its
+// caller. If it is the probe, another call may probe the half-open breaker.
+func (b *Breaker) Release(t Token) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if t.probe && t.generation == b.generation {
+		b.probedAt = time.Time{}
+	}
+}
+
+// Failure records a failed call. The breaker opens once the failures reach
+// the threshold, and opens again right away if its probe fails while it is
+// half-open. The calls allowed before the breaker last changed only count
+// as failures.
+func (b *Breaker) Failure(t Token) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if t.generation != b.generation {
+		return
+	}
+	if t.probe || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.probedAt = time.Time{}
+		b.generation++
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := State{State: b.state(time.Now()), Failures: b.failures}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	return s
+}
+
+func (b *Breaker) state(now time.Time) string {
+	switch {
+	case b.openedAt.IsZero():
+		return StateClosed
+	case now.Sub(b.openedAt) < b.cooldown:
+		return StateOpen
+	default:
+		return StateHalfOpen
+	}
+}
@@ -0,0 +1,136 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+const cooldown = 50 * time.Millisecond
+
+// halfOpen returns a breaker opened by a failure whose cooldown is over,
+// along with the token of a call allowed before it opened.
+func halfOpen(t *testing.T) (*Breaker, Token) {
+	t.Helper()
+
+	b := New(1, cooldown)
+	early, _ := b.Allow()
+	failed, _ := b.Allow()
+	b.Failure(failed)
+	if state := b.State().State; state != StateOpen {
+		t.Fatalf("got state %s after a failure, want %s", state, StateOpen)
+	}
+	time.Sleep(cooldown)
+	return b, early
+}
+
+func TestHalfOpenSingleProbe(t *testing.T) {
+	tests := []struct {
+		name string
+		// end ends the calls, given the probe and the call allowed
+		// before the breaker opened
+		end       func(b *Breaker, probe Token, early Token)
+		wantState string
+		// wantProbe tells whether another probe is allowed afterwards
+		wantProbe bool
+	}{
+		{
+			name:      "probe still running",
+			end:       func(b *Breaker, probe Token, early Token) {},
+			wantState: StateHalfOpen,
+		},
+		{
+			name:      "probe succeeds",
+			end:       func(b *Breaker, probe Token, early Token) { b.Success(probe) },
+			wantState: StateClosed,
+			wantProbe: true,
+		},
+		{
+			name:      "probe fails",
+			end:       func(b *Breaker, probe Token, early Token) { b.Failure(probe) },
+			wantState: StateOpen,
+		},
+		{
+			name:      "probe released",
+			end:       func(b *Breaker, probe Token, early Token) { b.Release(probe) },
+			wantState: StateHalfOpen,
+			wantProbe: true,
+		},
+		{
+			name:      "earlier call succeeds",
+			end:       func(b *Breaker, probe Token, early Token) { b.Success(early) },
+			wantState: StateHalfOpen,
+		},
+		{
+			name:      "earlier call fails",
+			end:       func(b *Breaker, probe Token, early Token) { b.Failure(early) },
+			wantState: StateHalfOpen,
+		},
+		{
+			name:      "earlier call released",
+			end:       func(b *Breaker, probe Token, early Token) { b.Release(early) },
+			wantState: StateHalfOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, early := halfOpen(t)
+
+			probe, ok := b.Allow()
+			if !ok {
+				t.Fatal("the probe is not allowed")
+			}
+			if _, ok := b.Allow(); ok {
+				t.Fatal("a second probe is allowed while the first one runs")
+			}
+
+			tt.end(b, probe, early)
+
+			if state := b.State().State; state != tt.wantState {
+				t.Errorf("got state %s, want %s", state, tt.wantState)
+			}
+			if _, ok := b.Allow(); ok != tt.wantProbe {
+				t.Errorf("got another call allowed %v, want %v", ok, tt.wantProbe)
+			}
+		})
+	}
+}
+
+func TestExpiredProbe(t *testing.T) {
+	b, _ := halfOpen(t)
+
+	stale, _ := b.Allow()
+	time.Sleep(cooldown)
+	probe, ok := b.Allow()
+	if !ok {
+		t.Fatal("no probe is allowed once the previous one has expired")
+	}
+
+	b.Success(stale)
+	if state := b.State().State; state != StateHalfOpen {
+		t.Errorf("got state %s after the expired probe succeeded, want %s", state, StateHalfOpen)
+	}
+
+	b.Success(probe)
+	if state := b.State().State; state != StateClosed {
+		t.Errorf("got state %s after the probe succeeded, want %s", state, StateClosed)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	b := New(3, time.Minute)
+	for i := range 3 {
+		token, ok := b.Allow()
+		if !ok {
+			t.Fatalf("call %d is not allowed before the threshold", i+1)
+		}
+		b.Failure(token)
+	}
+
+	if _, ok := b.Allow(); ok {
+		t.Error("a call is allowed after reaching the threshold")
+	}
+	if s := b.State(); s.State != StateOpen || s.Failures != 3 {
+		t.Errorf("got state %s with %d failures, want %s with 3", s.State, s.Failures, StateOpen)
+	}
+}
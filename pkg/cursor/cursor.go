@@ -0,0 +1,41 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Cursor points at a row of an ordered listing. It holds the values of the
+// sort keys of that row and the sort order they belong to, nil for NULL values.
+type Cursor struct {
+	Sort string    `json:"s"`
+	Keys []*string `json:"k"`
+}
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Encode turns the cursor into an opaque URL-safe token.
+func Encode(c Cursor) string {
+	js, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// Decode parses a token produced by Encode.
+func Decode(token string) (Cursor, error) {
+	var c Cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalid
+	}
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || len(c.Keys) == 0 {
+		return c, ErrInvalid
+	}
+	return c, nil
+}
@@ -43,6 +43,53 @@ func BadRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
 	ErrorResponse(w, r, http.StatusBadRequest, map[string]map[string]string{"errors": {"message": "bad request", "error": err.Error()}})
 }
 
+func EditConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "unable to update the record due to an edit conflict, please try again"
+	ErrorResponse(w, r, http.StatusConflict, map[string]map[string]string{"errors": {"message": message}})
+}
+
+// DuplicateSongResponse reports the ID of the song the group already has
+// with the same title.
+func DuplicateSongResponse(w http.ResponseWriter, r *http.Request, id uint64) {
+	message := "the group already has a song with this title"
+	ErrorResponse(w, r, http.StatusConflict, map[string]map[string]any{"errors": {"message": message, "id": id}})
+}
+
+func PreconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the record has been changed since it was fetched, please fetch it again"
+	ErrorResponse(w, r, http.StatusPreconditionFailed, map[string]map[string]string{"errors": {"message": message}})
+}
+
+func TimeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	LogError(r, err)
+	message := "the server did not complete the request in time, please try again later"
+	ErrorResponse(w, r, http.StatusGatewayTimeout, map[string]map[string]string{"errors": {"message": message}})
+}
+
+func BadGatewayResponse(w http.ResponseWriter, r *http.Request, err error) {
+	LogError(r, err)
+	message := "the external API did not respond properly, please try again later"
+	ErrorResponse(w, r, http.StatusBadGateway, map[string]map[string]string{"errors": {"message": message}})
+}
+
+func ServiceUnavailableResponse(w http.ResponseWriter, r *http.Request, err error) {
+	LogError(r, err)
+	message := "the external API is unavailable, please try again later"
+	ErrorResponse(w, r, http.StatusServiceUnavailable, map[string]map[string]string{"errors": {"message": message}})
+}
+
+// StatusClientClosedRequest is the non-standard status used when the client
+// went away before the response was ready.
+const StatusClientClosedRequest = 499
+
+func ClientClosedRequestResponse(w http.ResponseWriter, r *http.Request) {
+	logger.PrintInfo("client closed request", map[string]any{
+		"request_method": r.Method,
+		"request_url":    r.URL.String(),
+	})
+	w.WriteHeader(StatusClientClosedRequest)
+}
+
 func FailedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
 	errors["message"] = "encountered errors"
 	ErrorResponse(w, r, http.StatusUnprocessableEntity, map[string]map[string]string{"errors": errors})
@@ -0,0 +1,84 @@
+// Package lru implements an in-memory cache of a limited size, evicting the
+// least recently used entries. Every entry also expires after its own TTL.
+package lru
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// Cache holds up to size entries. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[K]*list.Element
+}
+
+func New[K comparable, V any](size int) *Cache[K, V] {
+	return &Cache[K, V]{
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value of the key, ok is false if there is none
+// or it has expired.
+func (c *Cache[K, V]) Get(key K) (value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return value, false
+	}
+
+	e := el.Value.(*entry[K, V])
+	if !time.Now().Before(e.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return value, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores the value of the key for the ttl, evicting the least recently
+// used entry if the cache is full.
+func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &entry[K, V]{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(e)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[K, V]).key)
+	}
+}
+
+// Len returns the number of entries, including the expired ones
+// that have not been evicted yet.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
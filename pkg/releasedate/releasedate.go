@@ -0,0 +1,71 @@
+package releasedate
+
+import (
+	"errors"
+	"time"
+)
+
+// Precision tells which part of a release date is known.
+type Precision string
+
+const (
+	PrecisionDay   Precision = "day"
+	PrecisionMonth Precision = "month"
+	PrecisionYear  Precision = "year"
+)
+
+var ErrInvalidFormat = errors.New("release date must be in format DD.MM.YYYY, MM.YYYY or YYYY")
+
+var layouts = map[Precision]string{
+	PrecisionDay:   "02.01.2006",
+	PrecisionMonth: "01.2006",
+	PrecisionYear:  "2006",
+}
+
+// Parse parses a release date in one of the "DD.MM.YYYY", "MM.YYYY" or "YYYY"
+// formats. The returned time is the first day of the period in UTC.
+func Parse(value string) (time.Time, Precision, error) {
+	for _, p := range []Precision{PrecisionDay, PrecisionMonth, PrecisionYear} {
+		if len(value) != len(layouts[p]) {
+			continue
+		}
+		t, err := time.Parse(layouts[p], value)
+		if err == nil {
+			return t, p, nil
+		}
+	}
+	return time.Time{}, "", ErrInvalidFormat
+}
+
+// Format formats the date back into the textual format of its precision.
+func Format(t time.Time, p Precision) string {
+	layout, ok := layouts[p]
+	if !ok {
+		layout = layouts[PrecisionDay]
+	}
+	return t.Format(layout)
+}
+
+// Period returns the half-open interval [from, to) covered by the date
+// with the given precision, e.g. the whole year for PrecisionYear.
+func Period(t time.Time, p Precision) (from time.Time, to time.Time) {
+	from = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case PrecisionYear:
+		return from, from.AddDate(1, 0, 0)
+	case PrecisionMonth:
+		return from, from.AddDate(0, 1, 0)
+	default:
+		return from, from.AddDate(0, 0, 1)
+	}
+}
+
+// ParsePeriod parses the value and returns the interval it covers.
+func ParsePeriod(value string) (from time.Time, to time.Time, err error) {
+	t, p, err := Parse(value)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	from, to = Period(t, p)
+	return from, to, nil
+}
@@ -0,0 +1,69 @@
+package releasedate
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		value     string
+		precision Precision
+		from, to  time.Time
+		err       error
+	}{
+		{value: "16.07.2006", precision: PrecisionDay, from: date(2006, 7, 16), to: date(2006, 7, 17)},
+		{value: "31.12.2006", precision: PrecisionDay, from: date(2006, 12, 31), to: date(2007, 1, 1)},
+		{value: "29.02.2008", precision: PrecisionDay, from: date(2008, 2, 29), to: date(2008, 3, 1)},
+		{value: "02.2008", precision: PrecisionMonth, from: date(2008, 2, 1), to: date(2008, 3, 1)},
+		{value: "12.2006", precision: PrecisionMonth, from: date(2006, 12, 1), to: date(2007, 1, 1)},
+		{value: "2006", precision: PrecisionYear, from: date(2006, 1, 1), to: date(2007, 1, 1)},
+		{value: "29.02.2007", err: ErrInvalidFormat},
+		{value: "13.2006", err: ErrInvalidFormat},
+		{value: "6.7.2006", err: ErrInvalidFormat},
+		{value: "2006-07-16", err: ErrInvalidFormat},
+		{value: "", err: ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			_, precision, err := Parse(tt.value)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if precision != tt.precision {
+				t.Errorf("got precision %s, want %s", precision, tt.precision)
+			}
+
+			from, to, err := ParsePeriod(tt.value)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("got period [%v, %v), want [%v, %v)", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, value := range []string{"16.07.2006", "07.2006", "2006"} {
+		t.Run(value, func(t *testing.T) {
+			parsed, precision, err := Parse(value)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if got := Format(parsed, precision); got != value {
+				t.Errorf("got %q, want %q", got, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package trigram
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Trigrams returns the set of trigrams of the text the way pg_trgm extracts
+// them: the text is lowercased and split into words of letters and digits,
+// each word padded with two spaces in front and one behind.
+func Trigrams(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// Similarity returns the share of trigrams the two texts have in common,
+// from 0 (nothing in common) to 1 (same trigrams), like pg_trgm similarity().
+func Similarity(a string, b string) float64 {
+	ta, tb := Trigrams(a), Trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
@@ -0,0 +1,56 @@
+package trigram
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Muse", want: []string{"  m", " mu", "mus", "use", "se "}},
+		{text: "a-ha", want: []string{"  a", " a ", "  h", " ha", "ha "}},
+		{text: "Би-2", want: []string{"  б", " би", "би ", "  2", " 2 "}},
+		{text: "!!!", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := slices.Sorted(maps.Keys(Trigrams(tt.text)))
+			want := slices.Sorted(slices.Values(tt.want))
+			if !slices.Equal(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{a: "Muse", b: "Muse", want: 1},
+		{a: "Muse", b: "MUSE!", want: 1},
+		{a: "Muse", b: "muses", want: 4.0 / 7},
+		{a: "Muse", b: "Blur", want: 0},
+		{a: "Muse", b: "", want: 0},
+		{a: "", b: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			got := Similarity(tt.a, tt.b)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if reverse := Similarity(tt.b, tt.a); reverse != got {
+				t.Errorf("got %v the other way round, want %v", reverse, got)
+			}
+		})
+	}
+}
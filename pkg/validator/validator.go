@@ -2,6 +2,7 @@ package validator
 
 import (
 	"regexp"
+	"slices"
 )
 
 var ReleaseYearRX = regexp.MustCompile(`^[0-9]{4}$`)
@@ -35,3 +36,7 @@ func (v *Validator) Check(ok bool, key, message string) {
 func (v *Validator) Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
 }
+
+func PermittedValue[T comparable](value T, permittedValues ...T) bool {
+	return slices.Contains(permittedValues, value)
+}